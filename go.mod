@@ -2,7 +2,10 @@ module hugo_rain
 
 go 1.21.0
 
-require github.com/muesli/termenv v0.16.0
+require (
+	github.com/muesli/termenv v0.16.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/aymanbagabas/go-osc52/v2 v2.0.1 // indirect
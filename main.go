@@ -2,18 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	_ "image/gif"  // Register GIF for --palette
+	_ "image/jpeg" // Register JPEG for --palette
+	"image/png"
 	"io"
 	"log"
+	"math"
+	"math/bits"
 	"math/rand"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
+
+	"gopkg.in/yaml.v3"
 )
 
 // === CONFIG ===
@@ -28,24 +48,85 @@ const (
 	defaultMaxDropLength    = 20
 	defaultReactivateChance = 0.01
 	defaultPauseChance      = 0.1
+	defaultBackdropOpacity  = 0.3
+	defaultOpacity          = 1.0
+	defaultTitleFormat      = "hugo_rain — {color}/{chars}"
+	defaultNightStart       = 21   // Hour night mode begins
+	defaultNightEnd         = 7    // Hour night mode ends
+	defaultNightTemp        = 3400 // Color temperature in Kelvin
+	defaultStaticHeight     = 24   // Still frame size when stdout is not a terminal
+	defaultStaticWidth      = 80
+)
+
+// Conservative settings applied by the --remote profile.
+const (
+	remoteFPS      = 5 // Lower frame rate for laggy links
+	remoteBatchGap = 6 // Unchanged cells bridged instead of emitting a cursor move
 )
 
+// Limits applied by --low-mem for embedded devices.
+const (
+	lowMemMaxDensity = 1.0 // At most one drop per column
+	lowMemGCPercent  = 25  // Collect garbage sooner to keep the heap small
+	lowMemRefresh    = 300 // Frames between full repaints, which heal digest collisions
+)
+
+// maxInterlace is the largest --interlace factor.
+const maxInterlace = 32
+
 // Config holds the configuration for the Matrix rain animation.
 type Config struct {
-	BaseColor        Color   // Base color for falling characters
-	FPS              int     // Frames per second for animation
-	Density          float64 // Number of character drops per column
-	CharSet          []rune  // Characters used in the animation
-	MinDropLength    int     // Minimum length of a drop's trail
-	MaxDropLength    int     // Maximum length of a drop's trail
-	ReactivateChance float64 // Probability of reactivating an inactive drop
-	PauseChance      float64 // Probability of pausing an active drop
-	Debug            bool    // Enable debug logging
+	BaseColor        Color         // Base color for falling characters
+	FPS              int           // Frames per second for animation
+	Density          float64       // Number of character drops per column
+	CharSet          []rune        // Characters used in the animation, nil until "auto" is resolved
+	MinDropLength    int           // Minimum length of a drop's trail
+	MaxDropLength    int           // Maximum length of a drop's trail
+	ReactivateChance float64       // Probability of reactivating an inactive drop
+	PauseChance      float64       // Probability of pausing an active drop
+	Debug            bool          // Enable debug logging
+	Latency          bool          // Record frame scheduling latency and report it on exit
+	MaxBandwidth     int           // Output budget in bytes per second (0 for unlimited)
+	Remote           bool          // Use the slow-link rendering profile
+	TrueColor        bool          // Emit 24-bit colors instead of the 256-color palette
+	SyncUpdates      bool          // Wrap each frame in synchronized-update sequences
+	BatchGap         int           // Max unchanged cells rewritten to avoid a cursor move (0 disables)
+	Backdrop         [][]rune      // Preserved screen content shown behind the rain
+	BackdropOpacity  float64       // Brightness of the backdrop (0-1)
+	Opacity          float64       // Opacity of the rain over the backdrop (0-1)
+	StartDelay       time.Duration // Blank time before the animation starts
+	FadeIn           time.Duration // Duration of the ramp from black to full density
+	ColorName        string        // Name of the color theme
+	CharSetName      string        // Name of the character set, "custom", or "auto" until resolved
+	FilterGlyphs     bool          // Drop glyphs the terminal cannot render once it has been probed
+	TitleFormat      string        // Window title template (empty leaves the title alone)
+	Dictionary       []string      // Words rained down the columns instead of single glyphs
+	Transliterate    bool          // Disguise the dictionary words as lookalikes from CharSet
+	Cipher           map[rune]rune // Lookalikes in CharSet for dictionary glyphs, set once CharSet is final
+	Seed             int64         // Random seed for reproducible runs
+	Resilient        bool          // Retry failed frames with backoff instead of exiting
+	VerifySync       bool          // Run a shadow simulation and compare frame checksums
+	Resync           bool          // Resynchronize the shadow simulation after a divergence
+	NightMode        bool          // Warm the palette during night hours
+	NightStart       int           // Hour (0-23) night mode begins
+	NightEnd         int           // Hour (0-23) night mode ends
+	NightTemp        int           // Color temperature of night mode in Kelvin
+	Static           bool          // Print a single composed still frame and exit
+	Palette          image.Image   // Image whose pixels color the drops, column by column
+	Takeover         bool          // Stop another instance drawing on the same terminal
+	Watchdog         time.Duration // Re-initialize the terminal after output stalls this long (0 disables)
+	PNG              string        // With Static, write the still frame to this PNG file instead
+	Stats            bool          // Print a session summary to stderr on exit
+	StatsOut         string        // Write the session summary as JSON to this file on exit
+	Gravity          bool          // Track the mouse; holding a button bends drops toward the pointer
+	LowMem           bool          // Minimize memory use: no previous frame copy, capped drops
+	Interlace        int           // Update each column once every this many steps
+	Layout           []Zone        // Screen zones from --layout, empty for one full-screen rain
 }
 
 // validate checks the configuration for validity.
 func (c *Config) validate() error {
-	if len(c.CharSet) == 0 {
+	if len(c.CharSet) == 0 && c.CharSetName != "auto" {
 		return errors.New("character set cannot be empty")
 	}
 	if c.FPS < 1 || c.FPS > 60 {
@@ -60,6 +141,37 @@ func (c *Config) validate() error {
 	if c.ReactivateChance < 0 || c.PauseChance < 0 {
 		return errors.New("invalid probability configuration")
 	}
+	if c.BackdropOpacity < 0 || c.BackdropOpacity > 1 {
+		return fmt.Errorf("backdrop opacity out of range (0-1): got %.2f", c.BackdropOpacity)
+	}
+	if c.Opacity < 0 || c.Opacity > 1 {
+		return fmt.Errorf("opacity out of range (0-1): got %.2f", c.Opacity)
+	}
+	if c.StartDelay < 0 || c.FadeIn < 0 {
+		return errors.New("start delay and fade-in cannot be negative")
+	}
+	if c.NightStart < 0 || c.NightStart > 23 || c.NightEnd < 0 || c.NightEnd > 23 {
+		return fmt.Errorf("night hours out of range (0-23): got %d-%d", c.NightStart, c.NightEnd)
+	}
+	if c.NightTemp < 1000 || c.NightTemp > 6500 {
+		return fmt.Errorf("night temperature out of range (1000-6500K): got %d", c.NightTemp)
+	}
+	if c.BatchGap < 0 {
+		return fmt.Errorf("diff batch gap cannot be negative: got %d", c.BatchGap)
+	}
+	if c.MaxBandwidth < 0 {
+		return fmt.Errorf("max bandwidth cannot be negative: got %d", c.MaxBandwidth)
+	}
+	if c.Interlace < 1 || c.Interlace > maxInterlace {
+		return fmt.Errorf("interlace out of range (1-%d): got %d", maxInterlace, c.Interlace)
+	}
+	minWatchdog := watchdogFrames * time.Second / time.Duration(c.FPS)
+	if minWatchdog < time.Second {
+		minWatchdog = time.Second
+	}
+	if c.Watchdog != 0 && c.Watchdog < minWatchdog {
+		return fmt.Errorf("watchdog out of range (0 or at least %v at %d fps): got %v", minWatchdog, c.FPS, c.Watchdog)
+	}
 	return nil
 }
 
@@ -128,13 +240,81 @@ func (p *ConfigParser) Parse() (cfg *Config, err error) {
 		listOptions bool
 		charSetName string
 		debug       bool
+		latency     bool
+		bandwidth   string
+		remote      bool
+		trueColor   bool
+		syncUpdates bool
+		batchGap    int
+		backdrop    string
+		backdropOp  float64
+		opacity     float64
+		startDelay  time.Duration
+		fadeIn      time.Duration
+		titleFormat string
+		filter      bool
+		dictionary  string
+		seed        int64
+		resilient   bool
+		verifySync  bool
+		resync      bool
+		nightMode   bool
+		nightStart  int
+		nightEnd    int
+		nightTemp   int
+		static      bool
+		palette     string
+		translit    bool
+		takeover    bool
+		watchdog    time.Duration
+		pngPath     string
+		stats       bool
+		statsOut    string
+		gravity     bool
+		lowMem      bool
+		interlace   int
+		layout      string
 	)
 	flag.StringVar(&colorName, "color", defaultColor, "color theme (green, amber, red, etc.)")
 	flag.IntVar(&fps, "fps", defaultFPS, "frames per second (1-60)")
 	flag.Float64Var(&density, "density", defaultDensity, "drop density (0.1-3.0)")
 	flag.BoolVar(&listOptions, "list", false, "list available options")
-	flag.StringVar(&charSetName, "chars", defaultCharSet, "character set name or custom string")
+	flag.StringVar(&charSetName, "chars", defaultCharSet, "character set name, custom string, or auto")
 	flag.BoolVar(&debug, "debug", false, "enable debug logging")
+	flag.BoolVar(&latency, "latency", false, "measure frame scheduling jitter and print p50/p99 on exit")
+	flag.BoolVar(&stats, "stats", false, "print runtime, frames, average fps, bytes written and peak memory on exit")
+	flag.StringVar(&statsOut, "stats-out", "", "write the exit summary of --stats as JSON to a file")
+	flag.StringVar(&bandwidth, "max-bandwidth", "", "output budget for slow links, e.g. 50kbps (default unlimited)")
+	flag.BoolVar(&lowMem, "low-mem", false, "minimize memory for small devices (compact frame diffing, capped drop count)")
+	flag.IntVar(&interlace, "interlace", 1, "update and redraw a rotating 1/N of the columns each step to save CPU on huge terminals")
+	flag.BoolVar(&remote, "remote", false, "conservative rendering profile for SSH/mosh sessions")
+	flag.BoolVar(&trueColor, "truecolor", true, "use 24-bit colors (false uses the 256-color palette)")
+	flag.BoolVar(&syncUpdates, "sync", false, "wrap frames in synchronized-update sequences (for terminals that support them)")
+	flag.IntVar(&batchGap, "batch-gap", 0, "rewrite up to this many unchanged cells instead of moving the cursor (0 disables)")
+	flag.StringVar(&backdrop, "backdrop", "", "rain over preserved content: \"tmux\" (current pane) or a text file")
+	flag.Float64Var(&backdropOp, "backdrop-opacity", defaultBackdropOpacity, "brightness of the backdrop content (0-1)")
+	flag.Float64Var(&opacity, "opacity", defaultOpacity, "opacity of the rain over the backdrop (0-1)")
+	flag.DurationVar(&startDelay, "start-delay", 0, "wait this long on a blank screen before starting, e.g. 2s")
+	flag.DurationVar(&fadeIn, "fade-in", 0, "ramp brightness and density up from black over this long, e.g. 3s")
+	flag.StringVar(&dictionary, "dictionary", "", "rain random words from a word list, e.g. /usr/share/dict/words")
+	flag.BoolVar(&translit, "transliterate", false, "with --dictionary, disguise the words as lookalikes from --chars (reveal with d)")
+	flag.BoolVar(&takeover, "takeover", false, "stop another instance already running on this terminal instead of refusing to start")
+	flag.DurationVar(&watchdog, "watchdog", 0, "re-initialize the terminal when output or frames stall this long, e.g. 5s")
+	flag.BoolVar(&resilient, "resilient", false, "show frame errors and retry with backoff instead of exiting")
+	flag.BoolVar(&verifySync, "verify-sync", false, "run a lockstep shadow simulation and log frames whose checksums diverge")
+	flag.BoolVar(&resync, "resync", false, "with --verify-sync, resynchronize the shadow simulation after a divergence")
+	flag.BoolVar(&nightMode, "night-mode", false, "warm the palette to reduce blue light during night hours (toggle anytime with n)")
+	flag.IntVar(&nightStart, "night-start", defaultNightStart, "hour (0-23) night mode begins")
+	flag.IntVar(&nightEnd, "night-end", defaultNightEnd, "hour (0-23) night mode ends")
+	flag.IntVar(&nightTemp, "night-temp", defaultNightTemp, "night mode color temperature in Kelvin (1000-6500)")
+	flag.StringVar(&palette, "palette", "", "sample drop colors from an image (PNG, JPEG or GIF), mapped by column")
+	flag.BoolVar(&gravity, "gravity", false, "hold a mouse button to pull the drops toward the pointer")
+	flag.StringVar(&layout, "layout", "", "split the screen into zones described by a layout file (YAML)")
+	flag.BoolVar(&static, "static", false, "print a single composed still frame without animation")
+	flag.StringVar(&pngPath, "png", "", "with --static, write the still frame to a PNG file instead of printing it")
+	flag.Int64Var(&seed, "seed", 0, "random seed for reproducible runs (0 picks one)")
+	flag.BoolVar(&filter, "filter-glyphs", true, "before starting, probe the terminal and drop glyphs it cannot render (only when stdin and stdout are terminals)")
+	flag.StringVar(&titleFormat, "title-format", defaultTitleFormat, "window title while running ({color}, {chars}, {fps}, {density}); empty to disable")
 	flag.Parse()
 
 	if listOptions {
@@ -146,11 +326,64 @@ func (p *ConfigParser) Parse() (cfg *Config, err error) {
 		return nil, fmt.Errorf("unknown color theme: %s", colorName)
 	}
 
-	charSet, err := p.resolveCharSet(charSetName)
+	// "auto" and glyph filtering need to probe the terminal, which is left
+	// to resolveGlyphs once it has been locked.
+	var charSet []rune
+	if strings.ToLower(charSetName) == "auto" {
+		charSetName = "auto"
+	} else if charSet, err = p.resolveCharSet(charSetName); err != nil {
+		return nil, err
+	}
+	if pngPath != "" && !static {
+		return nil, errors.New("--png requires --static")
+	}
+	if translit && dictionary == "" {
+		return nil, errors.New("--transliterate requires --dictionary")
+	}
+	var words []string
+	if dictionary != "" {
+		if words, err = loadDictionary(dictionary); err != nil {
+			return nil, err
+		}
+		if !translit {
+			charSetName, charSet = "dictionary", dictionaryCharSet(words)
+		}
+	}
+
+	var zones []Zone
+	if layout != "" {
+		if verifySync {
+			return nil, errors.New("--layout cannot be combined with --verify-sync")
+		}
+		if zones, err = loadLayout(layout); err != nil {
+			return nil, err
+		}
+		for i := range zones {
+			if err := p.resolveZoneTheme(&zones[i], baseColor); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	maxBandwidth, err := parseBandwidth(bandwidth)
 	if err != nil {
 		return nil, err
 	}
 
+	var paletteImage image.Image
+	if palette != "" {
+		if paletteImage, err = loadPalette(palette); err != nil {
+			return nil, err
+		}
+	}
+
+	var backdropLines [][]rune
+	if backdrop != "" {
+		if backdropLines, err = loadBackdrop(backdrop); err != nil {
+			return nil, err
+		}
+	}
+
 	cfg = &Config{
 		BaseColor:        baseColor,
 		FPS:              fps,
@@ -161,6 +394,53 @@ func (p *ConfigParser) Parse() (cfg *Config, err error) {
 		ReactivateChance: defaultReactivateChance,
 		PauseChance:      defaultPauseChance,
 		Debug:            debug,
+		Latency:          latency,
+		MaxBandwidth:     maxBandwidth,
+		Remote:           remote,
+		TrueColor:        trueColor,
+		SyncUpdates:      syncUpdates,
+		BatchGap:         batchGap,
+		Backdrop:         backdropLines,
+		BackdropOpacity:  backdropOp,
+		Opacity:          opacity,
+		StartDelay:       startDelay,
+		FadeIn:           fadeIn,
+		ColorName:        strings.ToLower(colorName),
+		CharSetName:      p.charSetName(charSetName),
+		FilterGlyphs:     filter,
+		TitleFormat:      titleFormat,
+		Dictionary:       words,
+		Transliterate:    translit,
+		Seed:             seed,
+		Resilient:        resilient,
+		VerifySync:       verifySync,
+		Resync:           resync,
+		NightMode:        nightMode,
+		NightStart:       nightStart,
+		NightEnd:         nightEnd,
+		NightTemp:        nightTemp,
+		Static:           static,
+		Palette:          paletteImage,
+		Takeover:         takeover,
+		Watchdog:         watchdog,
+		PNG:              pngPath,
+		Stats:            stats,
+		StatsOut:         statsOut,
+		Gravity:          gravity,
+		LowMem:           lowMem,
+		Interlace:        interlace,
+		Layout:           zones,
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	if lowMem {
+		cfg.Density = math.Min(cfg.Density, lowMemMaxDensity)
+	}
+	if remote {
+		applyRemoteProfile(cfg, explicitFlags())
+	} else if os.Getenv("SSH_CONNECTION") != "" {
+		fmt.Fprintln(os.Stderr, "hint: SSH session detected; try --remote for smoother animation over slow links")
 	}
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -179,9 +459,30 @@ func (p *ConfigParser) listOptions() error {
 	for name := range p.configData.CharSets {
 		fmt.Println("  ", name)
 	}
+	fmt.Println("   auto (pick a set your locale and terminal can render)")
+	fmt.Println("   --dictionary FILE (rain words from a word list)")
+	fmt.Println("   --dictionary FILE --transliterate (disguise the words in the chosen set)")
 	fmt.Println("\nFPS: 1-60")
 	fmt.Println("Density: 0.1-3.0")
 	fmt.Println("Debug: enable with --debug")
+	fmt.Println("Latency report: enable with --latency")
+	fmt.Println("Session summary: --stats (stderr) or --stats-out FILE (JSON)")
+	fmt.Println("Max bandwidth: e.g. --max-bandwidth 50kbps (units: bps, kbps, mbps)")
+	fmt.Println("Low memory: --low-mem (routers, Pi Zero-class boards)")
+	fmt.Printf("Interlace: 1-%d (columns updated per step: 1/N)\n", maxInterlace)
+	fmt.Println("Remote profile: enable with --remote (recommended over SSH/mosh)")
+	fmt.Println("Backdrop: --backdrop tmux|FILE, --backdrop-opacity 0-1, --opacity 0-1")
+	fmt.Println("Intro: --start-delay 2s, --fade-in 3s")
+	fmt.Println("Seed: --seed N for a reproducible run")
+	fmt.Println("Resilient mode: enable with --resilient (retry instead of exiting on errors)")
+	fmt.Println("Determinism check: --verify-sync [--resync]")
+	fmt.Println("Night mode: --night-mode [--night-start 21 --night-end 7 --night-temp 3400]")
+	fmt.Println("Still frame: --static (for an MOTD or screenshot), --static --png FILE for an image")
+	fmt.Println("Palette image: --palette FILE (PNG, JPEG or GIF)")
+	fmt.Println("Gravity wells: --gravity (hold a mouse button over the rain)")
+	fmt.Println("Zones: --layout FILE (effects: rain, clock, stats, info, blank)")
+	fmt.Println("Stall watchdog: e.g. --watchdog 5s (for unattended kiosks)")
+	fmt.Println("Window title: --title-format \"hugo_rain — {color}/{chars}\" (empty to disable)")
 	return errors.New("list options requested")
 }
 
@@ -196,6 +497,232 @@ func (p *ConfigParser) resolveCharSet(name string) ([]rune, error) {
 	return []rune(name), nil
 }
 
+// autoCharSetCandidates lists the sets tried by --chars auto, most
+// distinctive first. The last one must render everywhere.
+var autoCharSetCandidates = []string{"matrix", "greek", "ascii"}
+
+// autoCharSetSamples is how many glyphs of each candidate are probed.
+const autoCharSetSamples = 6
+
+// autoCharSet picks the first candidate character set that the locale can
+// encode and whose sampled glyphs the terminal advances the cursor over as
+// expected. If the terminal cannot be probed, the fallback is used, since a
+// UTF-8 locale says nothing about the fonts installed.
+func (p *ConfigParser) autoCharSet() string {
+	fallback := autoCharSetCandidates[len(autoCharSetCandidates)-1]
+	if !localeIsUTF8() {
+		return fallback
+	}
+
+	var samples []rune
+	for _, name := range autoCharSetCandidates {
+		samples = append(samples, sampleGlyphs(p.configData.CharSets[name], autoCharSetSamples)...)
+	}
+	widths, err := probeGlyphWidths(samples)
+	if err != nil {
+		return fallback
+	}
+	for i, name := range autoCharSetCandidates {
+		ok := true
+		for j, r := range samples[i*autoCharSetSamples : (i+1)*autoCharSetSamples] {
+			if widths[i*autoCharSetSamples+j] != runeWidth(r) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return name
+		}
+	}
+	return fallback
+}
+
+// localeIsUTF8 reports whether the effective LC_CTYPE locale uses UTF-8.
+func localeIsUTF8() bool {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(key); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return false
+}
+
+// sampleGlyphs picks n glyphs spread evenly across a character set.
+func sampleGlyphs(set []rune, n int) []rune {
+	samples := make([]rune, n)
+	for i := range samples {
+		samples[i] = set[i*len(set)/n]
+	}
+	return samples
+}
+
+// maxProbedGlyphs caps how many distinct glyphs filterRenderable probes.
+const maxProbedGlyphs = 256
+
+// filterRenderable drops glyphs the terminal does not advance the cursor over
+// as expected, which usually means the font cannot render them. The set is
+// returned unchanged if it is plain ASCII, the terminal cannot be probed, or
+// nothing would be left.
+func filterRenderable(set []rune, debug bool) []rune {
+	var glyphs []rune
+	seen := make(map[rune]bool)
+	for _, r := range set {
+		if r >= utf8.RuneSelf && !seen[r] && len(glyphs) < maxProbedGlyphs {
+			seen[r] = true
+			glyphs = append(glyphs, r)
+		}
+	}
+	if len(glyphs) == 0 {
+		return set
+	}
+	widths, err := probeGlyphWidths(glyphs)
+	if err != nil {
+		if debug {
+			log.Printf("Skipping glyph filtering: %v", err)
+		}
+		return set
+	}
+
+	bad := make(map[rune]bool)
+	for i, r := range glyphs {
+		if widths[i] == 0 || widths[i] != runeWidth(r) {
+			bad[r] = true
+		}
+	}
+	kept := make([]rune, 0, len(set))
+	for _, r := range set {
+		if !bad[r] {
+			kept = append(kept, r)
+		}
+	}
+	if debug {
+		log.Printf("Glyph filtering dropped %d of %d glyphs", len(set)-len(kept), len(set))
+	}
+	if len(kept) == 0 {
+		return set
+	}
+	return kept
+}
+
+// resolveGlyphs settles the character sets that depend on the terminal: it
+// picks the set for --chars auto, drops glyphs the terminal cannot render when
+// cfg.FilterGlyphs is set, builds the transliteration cipher from what is
+// left, and does the same for the charsets of layout zones. It writes probes
+// to the terminal, so it must run after the terminal is locked.
+func (p *ConfigParser) resolveGlyphs(cfg *Config) error {
+	var autoName string
+	auto := func() string {
+		if autoName == "" {
+			autoName = p.autoCharSet()
+			if cfg.Debug {
+				log.Printf("Auto-selected character set %q", autoName)
+			}
+		}
+		return autoName
+	}
+
+	if cfg.CharSetName == "auto" {
+		cfg.CharSetName = auto()
+		cfg.CharSet = p.configData.CharSets[cfg.CharSetName]
+	}
+	if cfg.FilterGlyphs && cfg.CharSetName != "dictionary" {
+		cfg.CharSet = filterRenderable(cfg.CharSet, cfg.Debug)
+	}
+	if cfg.Transliterate {
+		cipher, err := transliteration(cfg.Dictionary, cfg.CharSetName, cfg.CharSet)
+		if err != nil {
+			return err
+		}
+		cfg.Cipher = cipher
+	}
+
+	for i := range cfg.Layout {
+		z := &cfg.Layout[i]
+		z.glyphs = cfg.CharSet
+		if z.CharSet == "" {
+			continue
+		}
+		if strings.ToLower(z.CharSet) == "auto" {
+			z.CharSet = auto()
+		}
+		glyphs, err := p.resolveCharSet(z.CharSet)
+		if err != nil {
+			return fmt.Errorf("zone %s: %w", z.Name, err)
+		}
+		if cfg.FilterGlyphs {
+			glyphs = filterRenderable(glyphs, cfg.Debug)
+		}
+		z.glyphs = glyphs
+	}
+	return nil
+}
+
+// charSetName returns the display name of a character set argument.
+func (p *ConfigParser) charSetName(name string) string {
+	if name == "dictionary" || name == "auto" {
+		return name
+	}
+	if _, ok := p.configData.CharSets[strings.ToLower(name)]; ok {
+		return strings.ToLower(name)
+	}
+	return "custom"
+}
+
+// explicitFlags returns the names of the flags set on the command line.
+func explicitFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+// applyRemoteProfile switches cfg to settings tuned for laggy connections,
+// leaving any option the user set explicitly untouched.
+func applyRemoteProfile(cfg *Config, explicit map[string]bool) {
+	if !explicit["fps"] {
+		cfg.FPS = remoteFPS
+	}
+	if !explicit["truecolor"] {
+		cfg.TrueColor = false
+	}
+	if !explicit["sync"] {
+		cfg.SyncUpdates = false
+	}
+	if !explicit["batch-gap"] {
+		cfg.BatchGap = remoteBatchGap
+	}
+}
+
+// parseBandwidth converts a bit rate such as "50kbps" into bytes per second.
+// An empty string means no limit.
+func parseBandwidth(raw string) (int, error) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	if value == "" {
+		return 0, nil
+	}
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		scale  float64
+	}{{"mbps", 1e6}, {"kbps", 1e3}, {"bps", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSuffix(value, unit.suffix)
+			multiplier = unit.scale
+			break
+		}
+	}
+	bitsPerSecond, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || bitsPerSecond <= 0 || math.IsInf(bitsPerSecond, 0) {
+		return 0, fmt.Errorf("invalid bandwidth %q (expected e.g. 50kbps)", raw)
+	}
+	// Round down to whole bytes, but never to 0, which would mean unlimited.
+	bytesPerSecond := bitsPerSecond * multiplier / 8
+	if bytesPerSecond < 1 {
+		return 0, fmt.Errorf("bandwidth out of range (at least 8bps): got %s", raw)
+	}
+	return int(bytesPerSecond), nil
+}
+
 // === TERMINAL ===
 
 // Terminal defines operations for interacting with the terminal.
@@ -206,16 +733,83 @@ type Terminal interface {
 }
 
 // StdTerminal implements Terminal for standard terminal operations.
-type StdTerminal struct{}
+type StdTerminal struct {
+	out   io.Writer        // Destination of the control sequences
+	title string           // Window title while running (empty leaves it alone)
+	mouse bool             // Report mouse buttons and drags as input
+	saved *syscall.Termios // Input mode to restore, nil if stdin is not a terminal
+}
 
 // Setup configures the terminal for animation (alternate buffer, hide cursor).
+// When a title is set, the current one is pushed onto the terminal's title
+// stack first so Restore can bring it back. Input is switched to unbuffered,
+// unechoed keys while signals such as Ctrl+C keep working.
 func (t *StdTerminal) Setup() {
-	fmt.Print("\x1b[?1049h\x1b[?25l")
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	if t.title != "" {
+		fmt.Fprintf(t.out, "\x1b[22;0t\x1b]0;%s\x07", t.title)
+	}
+	if t.mouse {
+		fmt.Fprint(t.out, "\x1b[?1002h\x1b[?1006h") // Button and drag events, SGR encoding
+	}
+	if saved, err := getTermios(syscall.Stdin); err == nil {
+		cbreak := *saved
+		cbreak.Lflag &^= syscall.ICANON | syscall.ECHO
+		cbreak.Cc[syscall.VMIN] = 1
+		cbreak.Cc[syscall.VTIME] = 0
+		if setTermios(syscall.Stdin, &cbreak) == nil {
+			t.saved = saved
+		}
+	}
 }
 
 // Restore resets the terminal to its original state.
 func (t *StdTerminal) Restore() {
-	fmt.Print("\x1b[?25h\x1b[?1049l")
+	if t.saved != nil {
+		setTermios(syscall.Stdin, t.saved)
+		t.saved = nil
+	}
+	if t.mouse {
+		fmt.Fprint(t.out, "\x1b[?1006l\x1b[?1002l")
+	}
+	if t.title != "" {
+		fmt.Fprint(t.out, "\x1b[23;0t")
+	}
+	fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+}
+
+// getTermios reads the terminal attributes of a file descriptor.
+func getTermios(fd int) (*syscall.Termios, error) {
+	var termios syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(&termios))); errno != 0 {
+		return nil, fmt.Errorf("failed to get terminal attributes: %w", syscall.Errno(errno))
+	}
+	return &termios, nil
+}
+
+// setTermios applies terminal attributes to a file descriptor.
+func setTermios(fd int, termios *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return fmt.Errorf("failed to set terminal attributes: %w", syscall.Errno(errno))
+	}
+	return nil
+}
+
+// formatTitle expands a window title template with the active settings and
+// strips control characters that would end the OSC sequence early.
+func formatTitle(cfg *Config) string {
+	title := strings.NewReplacer(
+		"{color}", cfg.ColorName,
+		"{chars}", cfg.CharSetName,
+		"{fps}", strconv.Itoa(cfg.FPS),
+		"{density}", strconv.FormatFloat(cfg.Density, 'g', -1, 64),
+	).Replace(cfg.TitleFormat)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, title)
 }
 
 // GetSize returns the terminal's height and width in characters.
@@ -231,6 +825,184 @@ func (t *StdTerminal) GetSize() (h, w int, err error) {
 	return int(sz.rows), int(sz.cols), nil
 }
 
+// === PROBE ===
+
+// probeTimeout bounds how long the terminal has to answer each read, in the
+// deciseconds used by the termios VTIME setting.
+const probeTimeout = 3
+
+// probeGlyphWidths prints each glyph at the start of the current line and
+// asks the terminal where the cursor ended up, returning how many columns it
+// advanced for each. The line is erased afterwards. It fails if stdin or stdout
+// is not a terminal or the terminal does not answer the cursor position
+// queries.
+func probeGlyphWidths(glyphs []rune) ([]int, error) {
+	if _, err := getTermios(syscall.Stdout); err != nil {
+		return nil, fmt.Errorf("stdout is not a terminal: %w", err)
+	}
+	saved, err := getTermios(syscall.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+	raw := *saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = probeTimeout
+	if err := setTermios(syscall.Stdin, &raw); err != nil {
+		return nil, err
+	}
+	defer setTermios(syscall.Stdin, saved)
+
+	// Send every query at once so probing costs a single round trip.
+	var b strings.Builder
+	for _, r := range glyphs {
+		b.WriteString("\r")
+		b.WriteRune(r)
+		b.WriteString("\x1b[6n")
+	}
+	b.WriteString("\r\x1b[K")
+	if _, err := os.Stdout.WriteString(b.String()); err != nil {
+		return nil, err
+	}
+
+	var reply []byte
+	buf := make([]byte, 256)
+	for strings.Count(string(reply), "R") < len(glyphs) {
+		n, err := os.Stdin.Read(buf)
+		if n == 0 || err != nil {
+			return nil, errors.New("terminal did not answer cursor position queries")
+		}
+		reply = append(reply, buf[:n]...)
+	}
+
+	widths := make([]int, 0, len(glyphs))
+	for _, answer := range strings.Split(string(reply), "R")[:len(glyphs)] {
+		var row, col int
+		start := strings.LastIndex(answer, "\x1b[")
+		if start < 0 {
+			return nil, fmt.Errorf("unexpected cursor position reply %q", answer)
+		}
+		if _, err := fmt.Sscanf(answer[start:], "\x1b[%d;%d", &row, &col); err != nil {
+			return nil, fmt.Errorf("unexpected cursor position reply %q", answer)
+		}
+		widths = append(widths, col-1)
+	}
+	return widths, nil
+}
+
+// === TTY LOCK ===
+
+// Timing of --takeover while waiting for the previous instance to exit.
+const (
+	takeoverPoll    = 50 * time.Millisecond
+	takeoverTimeout = 3 * time.Second
+)
+
+// lockTTY takes an advisory lock on the terminal behind stdout so that two
+// instances never interleave their escape sequences on the same screen. The
+// lock lives in a file named after the device and holds the owner's pid; it
+// is released when the returned file is closed or the process exits. When
+// stdout is not a terminal there is nothing to protect and no lock is taken.
+//
+// The pid is trusted enough to signal with --takeover, so the file must live
+// in a directory only the current user can write to, and is refused unless it
+// is a regular file of theirs.
+func lockTTY(takeover bool) (*os.File, error) {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(os.Stdout.Fd()), &st); err != nil {
+		return nil, fmt.Errorf("cannot stat stdout: %w", err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFCHR {
+		return nil, nil
+	}
+	dir, err := lockDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("hugo_rain-%x.lock", uint64(st.Rdev)))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|syscall.O_NOFOLLOW, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open lock file: %w", err)
+	}
+	if err := syscall.Fstat(int(f.Fd()), &st); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot stat lock file: %w", err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFREG || int(st.Uid) != os.Getuid() {
+		f.Close()
+		return nil, fmt.Errorf("lock file %s is not a regular file owned by the current user", path)
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		pid := lockOwner(f)
+		if !takeover {
+			f.Close()
+			return nil, fmt.Errorf("another instance (pid %d) is already running on this terminal; use --takeover to replace it", pid)
+		}
+		err = takeOver(f, pid)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("cannot lock %s: %w", path, err)
+	}
+
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return f, nil
+}
+
+// lockDir returns a directory for lock files that no other user can write to:
+// $XDG_RUNTIME_DIR if it is set, otherwise a directory of our own under the
+// temporary directory, created on first use.
+func lockDir() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("hugo_rain-%d", os.Getuid()))
+		if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("cannot create lock directory: %w", err)
+		}
+	}
+	var st syscall.Stat_t
+	if err := syscall.Lstat(dir, &st); err != nil {
+		return "", fmt.Errorf("cannot stat lock directory: %w", err)
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR || int(st.Uid) != os.Getuid() || st.Mode&0o022 != 0 {
+		return "", fmt.Errorf("lock directory %s must be a directory owned and only writable by the current user", dir)
+	}
+	return dir, nil
+}
+
+// lockOwner reads the pid recorded in a lock file, or 0 if there is none.
+func lockOwner(f *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	return pid
+}
+
+// takeOver asks the instance holding the lock to exit, which restores its
+// terminal state, and waits for the lock to become free.
+func takeOver(f *os.File, pid int) error {
+	if pid > 0 && pid != os.Getpid() {
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("cannot stop pid %d: %w", pid, err)
+		}
+	}
+	deadline := time.Now().Add(takeoverTimeout)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pid %d did not release the terminal within %v", pid, takeoverTimeout)
+		}
+		time.Sleep(takeoverPoll)
+	}
+}
+
 // === FRAME ===
 
 // Frame represents the in-memory terminal screen state.
@@ -238,6 +1010,7 @@ type Frame struct {
 	characters   [][]rune  // Characters to display
 	colors       [][]Color // Colors for each position
 	isBackground [][]bool  // Whether a position is background
+	changed      []bool    // Columns that may differ from the frame drawn before, nil if any may
 	height       int
 	width        int
 }
@@ -265,6 +1038,28 @@ func NewFrame(height, width int) *Frame {
 	}
 }
 
+// Checksum returns an FNV-1a hash of the frame's characters and colors.
+func (f *Frame) Checksum() uint64 {
+	h := fnv.New64a()
+	var cell [7]byte
+	for row := range f.characters {
+		for col, char := range f.characters[row] {
+			c := f.colors[row][col]
+			binary.LittleEndian.PutUint32(cell[:4], uint32(char))
+			cell[4], cell[5], cell[6] = c.R, c.G, c.B
+			h.Write(cell[:])
+		}
+	}
+	return h.Sum64()
+}
+
+// touch marks every column of the frame as possibly changed. Anything drawing
+// over a rendered frame must call it, as the engine otherwise redraws only the
+// columns the simulation moved and the screen diffs only those.
+func (f *Frame) touch() {
+	f.changed = nil
+}
+
 // clear resets the frame to its default state.
 func (f *Frame) clear() {
 	for i := range f.characters {
@@ -276,69 +1071,391 @@ func (f *Frame) clear() {
 	}
 }
 
-// === DROP ===
-
-// Drop represents a single falling character in the Matrix rain.
-type Drop struct {
-	Pos    int  // Current vertical position
-	Length int  // Length of the drop's trail
-	Char   rune // Character to display
-	Active bool // Whether the drop is currently falling
+// clearColumns resets the given columns to their default state.
+func (f *Frame) clearColumns(cols []int) {
+	for i := range f.characters {
+		for _, j := range cols {
+			f.characters[i][j] = ' '
+			f.isBackground[i][j] = true
+			f.colors[i][j] = Color{}
+		}
+	}
 }
 
-// NewDrop creates a new Drop with random initial state.
-func NewDrop(height, minLength, maxLength int, charSet []rune, random *rand.Rand) (*Drop, error) {
-	if len(charSet) == 0 {
-		return nil, errors.New("character set cannot be empty")
-	}
-	return &Drop{
-		Pos:    random.Intn(height) - random.Intn(height/2),
-		Length: random.Intn(maxLength-minLength+1) + minLength,
-		Char:   charSet[random.Intn(len(charSet))],
-		Active: true,
-	}, nil
+// === FRAME IMAGE ===
+
+// Size in pixels of one terminal cell in a FrameImage.
+const (
+	frameImageCellWidth  = 8
+	frameImageCellHeight = 16
+)
+
+// Layout of the dots that stand in for a glyph inside a cell.
+const (
+	glyphDotColumns = 3
+	glyphDotRows    = 5
+)
+
+// FrameImage presents a Frame as an image.Image so that standard imaging
+// code (image/png, image/gif, resizers) can consume engine output directly.
+// Each cell becomes a block of pixels; lacking a font, a glyph is drawn as a
+// grid of dots in the cell's color, picked from a hash of the rune so that
+// the same glyph always looks the same. Background cells are black.
+type FrameImage struct {
+	frame                 *Frame
+	cellWidth, cellHeight int
 }
 
-// === DROP MANAGER ===
+// NewFrameImage wraps a frame, scaling each cell to the given pixel size.
+func NewFrameImage(frame *Frame, cellWidth, cellHeight int) *FrameImage {
+	return &FrameImage{frame: frame, cellWidth: cellWidth, cellHeight: cellHeight}
+}
 
-// DropManager handles the creation and updating of drops.
-type DropManager struct {
-	drops            [][]*Drop
-	height, width    int
-	charSet          []rune
-	minDropLength    int
-	maxDropLength    int
-	density          float64
-	reactivateChance float64
-	pauseChance      float64
-	random           *rand.Rand
-	debug            bool
+// ColorModel implements image.Image.
+func (m *FrameImage) ColorModel() color.Model {
+	return color.RGBAModel
 }
 
-// NewDropManager creates a new DropManager with the given configuration.
-func NewDropManager(cfg *Config, random *rand.Rand) (*DropManager, error) {
-	return &DropManager{
-		drops:            nil,
-		height:           0,
-		width:            0,
-		charSet:          cfg.CharSet,
-		minDropLength:    cfg.MinDropLength,
-		maxDropLength:    cfg.MaxDropLength,
-		density:          cfg.Density,
-		reactivateChance: cfg.ReactivateChance,
-		pauseChance:      cfg.PauseChance,
-		random:           random,
-		debug:            cfg.Debug,
-	}, nil
+// Bounds implements image.Image.
+func (m *FrameImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, m.frame.width*m.cellWidth, m.frame.height*m.cellHeight)
 }
 
-// Resize adjusts the drop grid to the new dimensions.
+// At implements image.Image.
+func (m *FrameImage) At(x, y int) color.Color {
+	black := color.RGBA{A: 255}
+	if !(image.Point{x, y}.In(m.Bounds())) {
+		return black
+	}
+	row, col := y/m.cellHeight, x/m.cellWidth
+	if m.frame.isBackground[row][col] {
+		return black
+	}
+	// Locate the dot under the pixel, keeping a one pixel gap between dots
+	// when they are large enough to afford it.
+	dotWidth := max(m.cellWidth/glyphDotColumns, 1)
+	dotHeight := max(m.cellHeight/glyphDotRows, 1)
+	cx, cy := x%m.cellWidth, y%m.cellHeight
+	dx, dy := cx/dotWidth, cy/dotHeight
+	if dx >= glyphDotColumns || dy >= glyphDotRows {
+		return black
+	}
+	if (dotWidth > 1 && cx%dotWidth == dotWidth-1) || (dotHeight > 1 && cy%dotHeight == dotHeight-1) {
+		return black
+	}
+	if glyphDots(m.frame.characters[row][col])&(1<<(dy*glyphDotColumns+dx)) == 0 {
+		return black
+	}
+	c := m.frame.colors[row][col]
+	return color.RGBA{R: c.R, G: c.G, B: c.B, A: 255}
+}
+
+// glyphDots returns the dot pattern standing in for a glyph, one bit per dot
+// in row-major order. Blanks have no dots; any other glyph has at least one.
+func glyphDots(r rune) uint32 {
+	if unicode.IsSpace(r) {
+		return 0
+	}
+	const all = 1<<(glyphDotColumns*glyphDotRows) - 1
+	dots := uint32(r) * 2654435761 >> 7 & all
+	if dots == 0 {
+		dots = all
+	}
+	return dots
+}
+
+// writePNG encodes a frame as a PNG file.
+func writePNG(path string, frame *Frame) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := png.Encode(f, NewFrameImage(frame, frameImageCellWidth, frameImageCellHeight)); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// === BACKDROP ===
+
+// backdropTabWidth is the tab stop used when expanding captured content.
+const backdropTabWidth = 8
+
+// loadBackdrop captures the content to rain over, either from the current tmux
+// pane or from a text file, as lines of printable runes.
+func loadBackdrop(source string) ([][]rune, error) {
+	var data []byte
+	var err error
+	if source == "tmux" {
+		if os.Getenv("TMUX") == "" {
+			return nil, errors.New("--backdrop tmux requires running inside tmux")
+		}
+		data, err = exec.Command("tmux", "capture-pane", "-p").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture tmux pane: %w", err)
+		}
+	} else if data, err = os.ReadFile(source); err != nil {
+		return nil, fmt.Errorf("failed to read backdrop: %w", err)
+	}
+
+	var lines [][]rune
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		var runes []rune
+		for _, r := range line {
+			switch {
+			case r == '\t':
+				for pad := backdropTabWidth - len(runes)%backdropTabWidth; pad > 0; pad-- {
+					runes = append(runes, ' ')
+				}
+			case unicode.IsPrint(r) && runeWidth(r) == 1:
+				runes = append(runes, r)
+			case unicode.IsPrint(r):
+				runes = append(runes, '?') // Keep the grid aligned
+			}
+		}
+		lines = append(lines, runes)
+	}
+	return lines, nil
+}
+
+// === DICTIONARY ===
+
+// loadDictionary reads a word list with one word per line, skipping blank
+// lines and words containing glyphs that do not occupy a terminal column.
+func loadDictionary(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		word := strings.TrimSpace(line)
+		if word != "" && strings.IndexFunc(word, func(r rune) bool {
+			return !unicode.IsPrint(r) || runeWidth(r) == 0
+		}) < 0 {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("dictionary %s contains no usable words", path)
+	}
+	return words, nil
+}
+
+// dictionaryCharSet returns the distinct glyphs used by a word list.
+func dictionaryCharSet(words []string) []rune {
+	var set []rune
+	seen := make(map[rune]bool)
+	for _, word := range words {
+		for _, r := range word {
+			if !seen[r] {
+				seen[r] = true
+				set = append(set, r)
+			}
+		}
+	}
+	return set
+}
+
+// wordStream joins random words, separated by spaces, until the stream holds
+// at least minLength glyphs.
+func wordStream(words []string, minLength int, random *rand.Rand) []rune {
+	var stream []rune
+	for len(stream) < minLength {
+		if len(stream) > 0 {
+			stream = append(stream, ' ')
+		}
+		stream = append(stream, []rune(words[random.Intn(len(words))])...)
+	}
+	return stream
+}
+
+// === TRANSLITERATION ===
+
+// lookalikes maps Latin letters and digits to visually similar glyphs of the
+// built-in character sets. Letters without an entry fall back to a glyph
+// picked by hash, so the same letter always gets the same disguise.
+var lookalikes = map[string]map[rune]rune{
+	"matrix": {
+		'a': 'ﾑ', 'c': 'ｺ', 'e': 'ｴ', 'f': 'ｷ', 'h': 'ﾋ', 'i': 'ｲ', 'j': 'ﾌ',
+		'k': 'ｹ', 'l': 'ﾚ', 'm': 'ﾊ', 'n': 'ﾝ', 'o': 'ﾛ', 'p': 'ｱ', 'r': 'ﾅ',
+		's': 'ｻ', 't': 'ﾃ', 'u': 'ﾘ', 'w': 'ﾜ', 'x': 'ﾒ', 'y': 'ﾘ', 'z': 'ｽ',
+		'0': 'ﾛ', '1': 'ｲ', '7': 'ﾌ',
+	},
+	"greek": {
+		'a': 'α', 'b': 'β', 'd': 'δ', 'e': 'ε', 'f': 'φ', 'h': 'η', 'i': 'ι',
+		'k': 'κ', 'l': 'λ', 'm': 'μ', 'n': 'η', 'o': 'ο', 'p': 'ρ', 'q': 'θ',
+		's': 'σ', 't': 'τ', 'u': 'υ', 'v': 'ν', 'w': 'ω', 'x': 'χ', 'y': 'γ',
+		'z': 'ζ', 'A': 'Α', 'B': 'Β', 'E': 'Ε', 'H': 'Η', 'I': 'Ι', 'K': 'Κ',
+		'M': 'Μ', 'N': 'Ν', 'O': 'Ο', 'P': 'Ρ', 'T': 'Τ', 'X': 'Χ', 'Y': 'Υ',
+		'Z': 'Ζ', '0': 'Θ',
+	},
+	"cyrillic": {
+		'a': 'а', 'b': 'ь', 'c': 'с', 'd': 'д', 'e': 'е', 'h': 'н', 'k': 'к',
+		'l': 'л', 'm': 'м', 'n': 'п', 'o': 'о', 'p': 'р', 'r': 'г', 't': 'т',
+		'u': 'и', 'w': 'ш', 'x': 'х', 'y': 'у', 'A': 'А', 'B': 'В', 'C': 'С',
+		'E': 'Е', 'H': 'Н', 'K': 'К', 'M': 'М', 'O': 'О', 'P': 'Р', 'T': 'Т',
+		'X': 'Х', 'Y': 'У', '3': 'З',
+	},
+}
+
+// transliteration builds the cipher that disguises every glyph of a word list
+// as a single-width glyph of the given character set. Spaces are kept so the
+// words stay apart.
+func transliteration(words []string, name string, set []rune) (map[rune]rune, error) {
+	var narrow []rune
+	available := make(map[rune]bool)
+	for _, r := range set {
+		if runeWidth(r) == 1 {
+			narrow = append(narrow, r)
+			available[r] = true
+		}
+	}
+	if len(narrow) == 0 {
+		return nil, fmt.Errorf("character set %s has no single-width glyphs to transliterate into", name)
+	}
+	table := lookalikes[name]
+	cipher := make(map[rune]rune)
+	for _, r := range dictionaryCharSet(words) {
+		if r == ' ' {
+			continue
+		}
+		if alike, ok := table[r]; ok && available[alike] {
+			cipher[r] = alike
+		} else if alike, ok := table[unicode.ToLower(r)]; ok && available[alike] {
+			cipher[r] = alike
+		} else {
+			cipher[r] = narrow[uint32(r)*2654435761%uint32(len(narrow))]
+		}
+	}
+	return cipher, nil
+}
+
+// === PALETTE ===
+
+// loadPalette decodes the image that drop colors are sampled from.
+func loadPalette(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open palette: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode palette %s: %w", path, err)
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("palette %s is empty", path)
+	}
+	return img, nil
+}
+
+// samplePalette picks a color from the image column that corresponds to a
+// terminal column, at a random height.
+func samplePalette(img image.Image, col, width int, random *rand.Rand) Color {
+	bounds := img.Bounds()
+	x := bounds.Min.X + col*bounds.Dx()/max(width, 1)
+	y := bounds.Min.Y + random.Intn(bounds.Dy())
+	r, g, b, _ := img.At(x, y).RGBA()
+	return Color{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8)}
+}
+
+// === DROP ===
+
+// Drop represents a single falling character in the Matrix rain.
+type Drop struct {
+	Pos    int       // Current vertical position
+	Length int       // Length of the drop's trail
+	Char   rune      // Character to display
+	Active bool      // Whether the drop is currently falling
+	Glyphs []rune    // Word stream shown along the trail instead of Char, if set
+	Col    int       // Column the drop falls in
+	Color  Color     // Base color sampled from the palette image, if any
+	Path   []float64 // Columns a gravity well bent the trail to, by row modulo its length; empty when straight
+}
+
+// NewDrop creates a new Drop with random initial state.
+func NewDrop(height, minLength, maxLength int, charSet []rune, random *rand.Rand) (*Drop, error) {
+	if len(charSet) == 0 {
+		return nil, errors.New("character set cannot be empty")
+	}
+	return &Drop{
+		Pos:    random.Intn(height) - random.Intn(height/2),
+		Length: random.Intn(maxLength-minLength+1) + minLength,
+		Char:   charSet[random.Intn(len(charSet))],
+		Active: true,
+	}, nil
+}
+
+// === DROP MANAGER ===
+
+// DropManager handles the creation and updating of drops.
+type DropManager struct {
+	drops            [][]*Drop
+	height, width    int
+	charSet          []rune
+	minDropLength    int
+	maxDropLength    int
+	density          float64
+	reactivateChance float64
+	pauseChance      float64
+	random           *rand.Rand
+	debug            bool
+	words            []string    // Dictionary words for per-column word streams
+	palette          image.Image // Image drop colors are sampled from, if any
+	interlace        int         // Each column is updated once every this many steps
+	step             int         // Steps taken, selecting the columns to update
+	stepped          []bool      // Columns updated since the engine last drew them
+	wellRow          int         // Cell of the gravity well drops are pulled toward
+	wellCol          int
+	wellPull         float64 // Strength of the gravity well, 0 when there is none
+}
+
+// NewDropManager creates a new DropManager with the given configuration.
+func NewDropManager(cfg *Config, random *rand.Rand) (*DropManager, error) {
+	return &DropManager{
+		drops:            nil,
+		height:           0,
+		width:            0,
+		charSet:          cfg.CharSet,
+		minDropLength:    cfg.MinDropLength,
+		maxDropLength:    cfg.MaxDropLength,
+		density:          cfg.Density,
+		reactivateChance: cfg.ReactivateChance,
+		pauseChance:      cfg.PauseChance,
+		random:           random,
+		debug:            cfg.Debug,
+		words:            cfg.Dictionary,
+		palette:          cfg.Palette,
+		interlace:        cfg.Interlace,
+	}, nil
+}
+
+// copyState makes the drops of the manager identical to those of src.
+func (m *DropManager) copyState(src *DropManager) {
+	m.height, m.width = src.height, src.width
+	m.step = src.step
+	m.stepped = append(m.stepped[:0], src.stepped...)
+	m.drops = make([][]*Drop, len(src.drops))
+	for col, colDrops := range src.drops {
+		m.drops[col] = make([]*Drop, len(colDrops))
+		for i, drop := range colDrops {
+			copied := *drop
+			copied.Path = append([]float64(nil), drop.Path...)
+			m.drops[col][i] = &copied
+		}
+	}
+}
+
+// Resize adjusts the drop grid to the new dimensions.
 func (m *DropManager) Resize(height, width int) error {
 	if height == m.height && width == m.width {
 		return nil
 	}
 	m.height, m.width = height, width
 
+	m.stepped = make([]bool, width)
 	m.drops = make([][]*Drop, width)
 	for col := 0; col < width; col++ {
 		numDrops := int(m.density + 0.5)
@@ -351,6 +1468,8 @@ func (m *DropManager) Resize(height, width int) error {
 			if err != nil {
 				return err
 			}
+			drop.Col = col
+			m.decorate(drop)
 			m.drops[col][i] = drop
 		}
 	}
@@ -368,6 +1487,7 @@ func (m *DropManager) Update(d *Drop) {
 			d.Pos = 0
 			d.Length = m.random.Intn(m.maxDropLength-m.minDropLength+1) + m.minDropLength
 			d.Char = m.charSet[m.random.Intn(len(m.charSet))]
+			m.decorate(d)
 			if m.debug {
 				log.Printf("Reactivated drop at pos %d with char %q", d.Pos, d.Char)
 			}
@@ -375,10 +1495,13 @@ func (m *DropManager) Update(d *Drop) {
 		return
 	}
 	d.Pos++
+	m.pull(d)
 	if d.Pos-d.Length > m.height {
 		d.Pos = -d.Length
+		d.Path = d.Path[:0]
 		d.Length = m.random.Intn(m.maxDropLength-m.minDropLength+1) + m.minDropLength
 		d.Char = m.charSet[m.random.Intn(len(m.charSet))]
+		m.decorate(d)
 		if m.random.Float64() < m.pauseChance {
 			d.Active = false
 			if m.debug {
@@ -388,6 +1511,96 @@ func (m *DropManager) Update(d *Drop) {
 	}
 }
 
+// straighten snaps every drop bent by a gravity well back to its column.
+func (m *DropManager) straighten() {
+	for _, colDrops := range m.drops {
+		for _, drop := range colDrops {
+			drop.Path = drop.Path[:0]
+		}
+	}
+}
+
+// pull steers the head of a drop toward the gravity well and records the
+// column it passed each row at, so the trail follows the same curve. Cells
+// are pulled harder the closer they are, and the head eases toward that
+// offset rather than jumping, so trails passing near the well curve in and
+// straighten out again below it. Without a well, drops snap back straight.
+func (m *DropManager) pull(d *Drop) {
+	if m.wellPull == 0 {
+		d.Path = d.Path[:0]
+		return
+	}
+	if d.Pos < 0 {
+		return
+	}
+	if n := d.Length + 1; len(d.Path) != n {
+		if cap(d.Path) < n {
+			d.Path = make([]float64, n)
+		}
+		d.Path = d.Path[:n]
+		for i := range d.Path {
+			d.Path[i] = float64(d.Col)
+		}
+	}
+	n := len(d.Path)
+	x := d.Path[(d.Pos+n-1)%n]
+	// Terminal cells are about twice as tall as they are wide.
+	dx := (x - float64(m.wellCol)) / 2
+	dy := float64(d.Pos - m.wellRow)
+	pull := m.wellPull * math.Exp(-(dx*dx+dy*dy)/(gravityRadius*gravityRadius))
+	target := float64(d.Col) + float64(m.wellCol-d.Col)*pull
+	d.Path[d.Pos%n] = x + (target-x)*gravityFollow
+}
+
+// Step advances every active drop by one simulation tick. With interlacing,
+// only every Nth column, rotating from step to step, is advanced, by N rows at
+// once so the drops keep their speed.
+func (m *DropManager) Step() {
+	m.step++
+	for col, colDrops := range m.drops {
+		if m.interlace > 1 && col%m.interlace != m.step%m.interlace {
+			continue
+		}
+		m.stepped[col] = true
+		for _, drop := range colDrops {
+			for i := 0; i < m.interlace && drop != nil && drop.Active; i++ {
+				m.Update(drop)
+			}
+		}
+	}
+}
+
+// Compose arranges the drops for a still frame. Columns are kept or left empty
+// by their golden ratio rank, matching the density, and drop heads are spread
+// by the same sequence so trails cover the screen evenly instead of clumping.
+func (m *DropManager) Compose() {
+	keep := math.Min(m.density, 1)
+	for col, colDrops := range m.drops {
+		rank := goldenRank(col)
+		for i, drop := range colDrops {
+			drop.Active = rank < keep || i > 0
+			// Ranks of kept columns are uniform over [0, keep), so scaling them
+			// spreads the trail centers evenly from top to bottom. Extra drops
+			// in a column are spaced out from the first.
+			_, spread := math.Modf(rank/keep + float64(i)/float64(len(colDrops)))
+			drop.Pos = int(spread*float64(m.height)) + drop.Length/2
+		}
+	}
+}
+
+// decorate refreshes the optional parts of a (re)spawned drop: a word stream
+// in dictionary mode, stretching the trail to fit the words, and a color from
+// the palette image.
+func (m *DropManager) decorate(d *Drop) {
+	if len(m.words) > 0 {
+		d.Glyphs = wordStream(m.words, d.Length, m.random)
+		d.Length = len(d.Glyphs)
+	}
+	if m.palette != nil {
+		d.Color = samplePalette(m.palette, d.Col, m.width, m.random)
+	}
+}
+
 // Drops returns the current drop grid.
 func (m *DropManager) Drops() [][]*Drop {
 	return m.drops
@@ -405,6 +1618,40 @@ type Engine struct {
 	frameBuffer   *Frame
 	fps           int
 	debug         bool
+	resilient     bool          // Report terminal size errors instead of keeping the old size
+	backdrop      [][]rune      // Preserved content drawn beneath the drops
+	backdropColor Color         // Color of the backdrop content
+	origin        image.Point   // Screen cell of the frame's top-left corner, when drawing a layout zone
+	opacity       float64       // Opacity of the drops over the backdrop
+	dropColors    bool          // Color each drop from its own base color (palette mode)
+	cipher        map[rune]rune // Disguise for word-stream glyphs, if transliterating
+	decrypted     bool          // Show word streams as plain text despite the cipher
+	intensity     float64       // Fade level of the rain, from 0 (black) to 1 (full)
+	timeScale     float64       // Simulation steps per frame, 1 for real time
+	simClock      float64       // Simulation time owed but not yet stepped
+	columns       []bool        // Columns redrawn by the last render, shared with the frame buffer
+	redraw        []int         // Indexes of the columns being redrawn, reused across renders
+}
+
+// mirrorControls copies the settings of another engine that change while it
+// runs, through keys, the mouse or the fade-in.
+func (e *Engine) mirrorControls(src *Engine) {
+	e.SetIntensity(src.intensity)
+	e.SetTimeScale(src.timeScale)
+	e.SetWell(src.manager.wellRow, src.manager.wellCol, src.manager.wellPull)
+	e.SetDecrypted(src.decrypted)
+}
+
+// copyState makes the simulation state of the engine identical to that of
+// src, apart from the random source. Fields added to Engine or DropManager that
+// change while running must be copied here and in mirrorControls.
+func (e *Engine) copyState(src *Engine) {
+	e.height, e.width = src.height, src.width
+	e.frameBuffer = NewFrame(src.height, src.width)
+	e.simClock = src.simClock
+	e.columns = make([]bool, src.width)
+	e.mirrorControls(src)
+	e.manager.copyState(src.manager)
 }
 
 // NewEngine creates a new Engine with the given configuration.
@@ -425,7 +1672,15 @@ func NewEngine(cfg *Config, random *rand.Rand, terminal Terminal) (*Engine, erro
 		frameBuffer: nil,
 		fps:         cfg.FPS,
 		debug:       cfg.Debug,
+		resilient:   cfg.Resilient,
+		backdrop:    cfg.Backdrop,
+		opacity:     cfg.Opacity,
+		dropColors:  cfg.Palette != nil,
+		cipher:      cfg.Cipher,
+		intensity:   1,
+		timeScale:   1,
 	}
+	e.backdropColor = dim(Color{255, 255, 255}, cfg.BackdropOpacity)
 	e.trailColors = e.calcTrailColors(5)
 	return e, nil
 }
@@ -435,12 +1690,75 @@ func NewEngine(cfg *Config, random *rand.Rand, terminal Terminal) (*Engine, erro
 func (e *Engine) calcTrailColors(steps int) []Color {
 	colors := make([]Color, steps)
 	for i := 0; i < steps; i++ {
-		fade := 1.0 - float64(i)/float64(steps)*0.8
-		colors[i] = dim(e.baseColor, fade)
+		colors[i] = dim(e.baseColor, trailFade(i, steps))
 	}
 	return colors
 }
 
+// trailFade returns the brightness of the i-th of steps trail segments.
+func trailFade(i, steps int) float64 {
+	return 1.0 - float64(i)/float64(steps)*0.8
+}
+
+// SetIntensity sets the fade level of the rain. Below 1, drops are dimmed and
+// only a matching fraction of columns is drawn.
+func (e *Engine) SetIntensity(intensity float64) {
+	intensity = math.Max(0, math.Min(1, intensity))
+	if intensity != e.intensity {
+		e.intensity = intensity
+		e.repaint() // Columns drawn at the old intensity are dimmed differently
+	}
+}
+
+// SetTimeScale sets how fast the simulation runs relative to the frame rate,
+// so frames keep rendering at full rate while drops slow down or speed up.
+func (e *Engine) SetTimeScale(scale float64) {
+	e.timeScale = math.Max(0, scale)
+}
+
+// SetWell places a gravity well that pulls the falling drops toward a cell. A
+// pull of 0 removes it, and the drops snap back to their columns.
+func (e *Engine) SetWell(row, col int, pull float64) {
+	m := e.manager
+	pull = math.Max(0, math.Min(pull, gravityMaxPull))
+	if pull == 0 && m.wellPull > 0 {
+		m.straighten()
+		e.repaint()
+	}
+	m.wellRow, m.wellCol, m.wellPull = row, col, pull
+}
+
+// SetDecrypted switches transliterated word streams between their disguise
+// and plain text.
+func (e *Engine) SetDecrypted(decrypted bool) {
+	if decrypted != e.decrypted {
+		e.decrypted = decrypted
+		e.repaint()
+	}
+}
+
+// ToggleDecrypted flips word streams between their disguise and plain text.
+func (e *Engine) ToggleDecrypted() {
+	e.SetDecrypted(!e.decrypted)
+}
+
+// repaint makes the next render redraw every column.
+func (e *Engine) repaint() {
+	if e.frameBuffer != nil {
+		e.frameBuffer.touch()
+	}
+}
+
+// columnVisible reports whether a column is drawn at the current intensity.
+// Columns are ranked with the golden ratio sequence so that the visible ones
+// stay evenly spread while the rain fades in.
+func (e *Engine) columnVisible(col int) bool {
+	if e.intensity >= 1 {
+		return true
+	}
+	return goldenRank(col) < e.intensity
+}
+
 // Resize adjusts the engine's dimensions and frame buffer.
 func (e *Engine) Resize(height, width int) error {
 	if err := e.manager.Resize(height, width); err != nil {
@@ -448,36 +1766,91 @@ func (e *Engine) Resize(height, width int) error {
 	}
 	e.height, e.width = height, width
 	e.frameBuffer = NewFrame(height, width)
+	e.columns = make([]bool, width)
 	return nil
 }
 
 // NextFrame generates the next animation frame.
 func (e *Engine) NextFrame() (*Frame, error) {
-	if h, w, err := e.terminal.GetSize(); err == nil && (h != e.height || w != e.width) {
+	h, w, err := e.terminal.GetSize()
+	if err != nil {
+		if e.resilient {
+			return nil, fmt.Errorf("failed to get terminal size: %w", err)
+		}
+	} else if h != e.height || w != e.width {
 		if err := e.Resize(h, w); err != nil {
 			return nil, err
 		}
 	}
 
-	e.frameBuffer.clear()
-	drops := e.manager.Drops()
-	for col, colDrops := range drops {
-		for _, drop := range colDrops {
-			if drop == nil || !drop.Active {
-				continue
-			}
-			e.manager.Update(drop)
-			if drop.Active {
-				e.drawDrop(drop, e.frameBuffer, col)
-			}
-		}
+	e.simClock += e.timeScale
+	for ; e.simClock >= 1; e.simClock-- {
+		e.manager.Step()
 	}
+
+	e.render()
 	if e.debug {
 		log.Printf("Generated frame with %dx%d dimensions", e.height, e.width)
 	}
 	return e.frameBuffer, nil
 }
 
+// ComposeFrame generates a still frame with balanced drop placement, rather
+// than whatever a random instant of the animation happens to look like.
+func (e *Engine) ComposeFrame() *Frame {
+	e.manager.Compose()
+	e.repaint()
+	e.render()
+	return e.frameBuffer
+}
+
+// render draws the backdrop and the current drops into the frame buffer.
+//
+// When interlacing, only the columns stepped since the last render are
+// redrawn, the rest of the frame buffer being left as it was. That takes an
+// untouched frame buffer, full intensity, and no gravity well bending trails
+// across columns; otherwise everything is redrawn.
+func (e *Engine) render() {
+	frame, m := e.frameBuffer, e.manager
+	if m.interlace > 1 && frame.changed != nil && e.intensity >= 1 && m.wellPull == 0 {
+		copy(e.columns, m.stepped)
+		e.redraw = e.redraw[:0]
+		for col, stepped := range e.columns {
+			if stepped {
+				e.redraw = append(e.redraw, col)
+			}
+		}
+		frame.clearColumns(e.redraw)
+		for _, col := range e.redraw {
+			e.drawBackdropColumn(frame, col)
+			e.drawColumn(frame, col)
+		}
+	} else {
+		for col := range e.columns {
+			e.columns[col] = true
+		}
+		frame.clear()
+		e.drawBackdrop(frame)
+		for col := range m.drops {
+			e.drawColumn(frame, col)
+		}
+	}
+	frame.changed = e.columns
+	clear(m.stepped)
+}
+
+// drawColumn draws the drops falling in a column.
+func (e *Engine) drawColumn(frame *Frame, col int) {
+	if !e.columnVisible(col) {
+		return
+	}
+	for _, drop := range e.manager.drops[col] {
+		if drop != nil && drop.Active {
+			e.drawDrop(drop, frame, col)
+		}
+	}
+}
+
 // getTrailColorIndex calculates the color index for a drop's trail position.
 func (e *Engine) getTrailColorIndex(pos, tail, length int) int {
 	dist := pos - tail
@@ -488,15 +1861,58 @@ func (e *Engine) getTrailColorIndex(pos, tail, length int) int {
 	return idx
 }
 
+// drawBackdrop paints the preserved content, dimmed, onto the frame.
+func (e *Engine) drawBackdrop(frame *Frame) {
+	for col := 0; col < frame.width; col++ {
+		e.drawBackdropColumn(frame, col)
+	}
+}
+
+// drawBackdropColumn paints a single column of the backdrop. The backdrop
+// lines up with the screen, so it is shifted by the origin of the engine's
+// frame on it.
+func (e *Engine) drawBackdropColumn(frame *Frame, col int) {
+	x := col + e.origin.X
+	for row := 0; row < frame.height && row+e.origin.Y < len(e.backdrop); row++ {
+		if line := e.backdrop[row+e.origin.Y]; x < len(line) && line[x] != ' ' {
+			frame.characters[row][col] = line[x]
+			frame.isBackground[row][col] = false
+			frame.colors[row][col] = e.backdropColor
+		}
+	}
+}
+
 // drawDrop renders a drop onto the frame with trail colors.
 func (e *Engine) drawDrop(drop *Drop, frame *Frame, col int) {
 	tail := drop.Pos - drop.Length
 	startRow := max(tail, 0)
 	endRow := min(drop.Pos, frame.height-1)
 	for row := startRow; row <= endRow; row++ {
-		frame.characters[row][col] = drop.Char
-		frame.isBackground[row][col] = false
-		frame.colors[row][col] = e.trailColors[e.getTrailColorIndex(drop.Pos, row, drop.Length)]
+		idx := e.getTrailColorIndex(drop.Pos, row, drop.Length)
+		color := e.trailColors[idx]
+		if e.dropColors {
+			color = dim(drop.Color, trailFade(idx, len(e.trailColors)))
+		}
+		if e.intensity < 1 {
+			color = dim(color, e.intensity)
+		}
+		x := col
+		if n := len(drop.Path); n > 0 {
+			x = min(max(int(math.Round(drop.Path[row%n])), 0), frame.width-1)
+		}
+		if e.opacity < 1 {
+			color = blend(color, frame.colors[row][x], e.opacity)
+		}
+		frame.characters[row][x] = drop.Char
+		if len(drop.Glyphs) > 0 {
+			glyph := drop.Glyphs[(row-tail)%len(drop.Glyphs)]
+			if disguise, ok := e.cipher[glyph]; ok && !e.decrypted {
+				glyph = disguise
+			}
+			frame.characters[row][x] = glyph
+		}
+		frame.isBackground[row][x] = false
+		frame.colors[row][x] = color
 	}
 }
 
@@ -514,6 +1930,89 @@ func brighten(c Color, factor float64) Color {
 	}
 }
 
+// kelvinFactors returns the per-channel multipliers that tint white to the
+// given color temperature, using Tanner Helland's approximation of the
+// blackbody curve.
+func kelvinFactors(kelvin int) [3]float64 {
+	temp := float64(kelvin) / 100
+	var r, g, b float64
+	if temp <= 66 {
+		r = 255
+		g = 99.4708025861*math.Log(temp) - 161.1195681661
+	} else {
+		r = 329.698727446 * math.Pow(temp-60, -0.1332047592)
+		g = 288.1221695283 * math.Pow(temp-60, -0.0755148492)
+	}
+	switch {
+	case temp >= 66:
+		b = 255
+	case temp <= 19:
+		b = 0
+	default:
+		b = 138.5177312231*math.Log(temp-10) - 305.0447927307
+	}
+	factor := func(v float64) float64 {
+		return math.Max(0, math.Min(255, v)) / 255
+	}
+	return [3]float64{factor(r), factor(g), factor(b)}
+}
+
+// blend mixes two colors, taking the given fraction (0-1) of the first.
+func blend(a, b Color, t float64) Color {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x)*t + float64(y)*(1-t))
+	}
+	return Color{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B)}
+}
+
+// ansi256Levels are the channel intensities of the 6x6x6 color cube in the
+// xterm 256-color palette.
+var ansi256Levels = [6]int{0, 95, 135, 175, 215, 255}
+
+// toANSI256 returns the xterm 256-color palette index closest to c, choosing
+// between the color cube and the grayscale ramp.
+func toANSI256(c Color) int {
+	nearestLevel := func(v uint8) int {
+		best := 0
+		for i, level := range ansi256Levels {
+			if abs(int(v)-level) < abs(int(v)-ansi256Levels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearestLevel(c.R), nearestLevel(c.G), nearestLevel(c.B)
+	cube := 16 + 36*r + 6*g + b
+
+	gray := (int(c.R) + int(c.G) + int(c.B)) / 3
+	grayIdx := min(max((gray-8+5)/10, 0), 23)
+	if colorDistance(c, ansi256Color(232+grayIdx)) < colorDistance(c, ansi256Color(cube)) {
+		return 232 + grayIdx
+	}
+	return cube
+}
+
+// ansi256Color returns the RGB value of a 256-color palette index from the
+// color cube or grayscale ramp.
+func ansi256Color(idx int) Color {
+	if idx >= 232 {
+		v := uint8(8 + 10*(idx-232))
+		return Color{v, v, v}
+	}
+	idx -= 16
+	return Color{
+		R: uint8(ansi256Levels[idx/36]),
+		G: uint8(ansi256Levels[idx/6%6]),
+		B: uint8(ansi256Levels[idx%6]),
+	}
+}
+
+// colorDistance returns the squared Euclidean distance between two colors.
+func colorDistance(a, b Color) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
 // dim reduces the brightness of a color by a factor.
 func dim(c Color, factor float64) Color {
 	return Color{
@@ -529,112 +2028,1476 @@ func dim(c Color, factor float64) Color {
 type Screen struct {
 	out           io.Writer
 	previousFrame *Frame
+	colorStep     int    // Quantize color channels to multiples of this step (0 or 1 disables)
+	skipColorOnly bool   // Ignore cells whose character is unchanged when delta rendering
+	frameBytes    int    // Bytes written by the most recent Draw
+	totalBytes    int64  // Bytes written since the screen was created
+	trueColor     bool   // Emit 24-bit colors rather than 256-color palette indexes
+	syncUpdates   bool   // Wrap each frame in synchronized-update sequences
+	batchGap      int    // Max unchanged cells rewritten instead of moving the cursor
+	buf           []byte // Output of the frame being rendered, reused across frames
+	cols          []int  // Columns of the frame being diffed, reused across frames
+
+	// In low-memory mode, cells are diffed against a two-byte digest of what
+	// the terminal shows instead of a copy of the previous frame.
+	lowMem     bool
+	marks      []uint16 // Digest of each cell shown, row by row
+	marksWidth int
+	frames     int // Frames drawn, scheduling the full repaints
 }
 
 // NewScreen creates a new Screen with the given output writer.
 func NewScreen(out io.Writer) *Screen {
-	return &Screen{out: out}
+	return &Screen{out: out, trueColor: true}
 }
 
 // Draw renders a frame to the terminal, using delta rendering when possible.
 func (s *Screen) Draw(frame *Frame) {
+	s.frameBytes = 0
+	if s.lowMem {
+		s.frames++
+		if len(s.marks) != frame.height*frame.width || s.marksWidth != frame.width || s.frames%lowMemRefresh == 0 {
+			s.fullRender(frame)
+			s.markFrame(frame)
+		} else {
+			s.deltaRender(frame)
+		}
+		return
+	}
 	if s.previousFrame == nil || s.previousFrame.height != frame.height || s.previousFrame.width != frame.width {
 		s.fullRender(frame)
 		s.previousFrame = NewFrame(frame.height, frame.width)
 		s.copyFrame(frame, s.previousFrame)
 	} else {
 		s.deltaRender(frame)
-		s.copyFrame(frame, s.previousFrame)
 	}
 }
 
-// writeColor writes ANSI color codes to the builder if needed.
-func (s *Screen) writeColor(b *strings.Builder, c Color, isColorSet *bool, currentColor *Color) bool {
-	if !*isColorSet || c != *currentColor {
-		b.WriteString(fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B))
-		*currentColor = c
-		*isColorSet = true
-		return true
+// Invalidate forgets what is on the terminal so the next Draw repaints the
+// whole frame.
+func (s *Screen) Invalidate() {
+	s.previousFrame = nil
+	s.marks = s.marks[:0]
+}
+
+// cellMark digests a cell for low-memory diffing: the high byte stands for
+// the glyph, or is 0 for background, and the low byte for the color. Distinct
+// cells can share a digest, leaving a stale cell until the next full repaint.
+func cellMark(char rune, c Color, isBackground bool) uint16 {
+	if isBackground {
+		return 0
 	}
-	return false
+	glyph := uint32(char)*2654435761>>24%255 + 1
+	shade := (uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)) * 2654435761 >> 24
+	return uint16(glyph<<8 | shade)
 }
 
-// fullRender draws the entire frame to the terminal.
-func (s *Screen) fullRender(frame *Frame) {
-	var b strings.Builder
-	// Estimate: 1 rune + up to 20 bytes for color codes per cell, plus newlines
-	b.Grow(frame.height * (frame.width*21 + 2))
-	b.WriteString("\x1b[H") // Move cursor to top-left
+// markFrame records the digests of a frame that was rendered in full.
+func (s *Screen) markFrame(frame *Frame) {
+	n := frame.height * frame.width
+	if cap(s.marks) < n {
+		s.marks = make([]uint16, n)
+	}
+	s.marks, s.marksWidth = s.marks[:n], frame.width
+	for row := 0; row < frame.height; row++ {
+		for col := 0; col < frame.width; col++ {
+			s.marks[row*frame.width+col] = cellMark(frame.characters[row][col], s.quantize(frame.colors[row][col]), frame.isBackground[row][col])
+		}
+	}
+}
+
+// update reports whether a cell differs from what the terminal shows, going by
+// the previous frame or, in low-memory mode, the digests, and records it as
+// shown if so. Color-only changes are ignored while skipColorOnly is set.
+func (s *Screen) update(frame *Frame, row, col int, char rune, color Color) bool {
+	isBackground := frame.isBackground[row][col]
+	if s.lowMem {
+		i := row*s.marksWidth + col
+		mark := cellMark(char, color, isBackground)
+		if mark == s.marks[i] || (s.skipColorOnly && mark>>8 == s.marks[i]>>8) {
+			return false
+		}
+		s.marks[i] = mark
+		return true
+	}
+	prev := s.previousFrame
+	if char == prev.characters[row][col] && (color == prev.colors[row][col] || s.skipColorOnly) {
+		return false
+	}
+	prev.characters[row][col] = char
+	prev.colors[row][col] = color
+	prev.isBackground[row][col] = isBackground
+	return true
+}
+
+// begin starts rendering a frame into the reused output buffer.
+func (s *Screen) begin() {
+	s.buf = s.buf[:0]
+	if s.syncUpdates {
+		s.buf = append(s.buf, "\x1b[?2026h"...)
+	}
+}
+
+// write sends the rendered frame to the terminal and accounts for its size.
+func (s *Screen) write() {
+	if s.syncUpdates {
+		s.buf = append(s.buf, "\x1b[?2026l"...)
+	}
+	n, _ := s.out.Write(s.buf)
+	s.frameBytes += n
+	s.totalBytes += int64(n)
+}
+
+// quantize reduces a color to the screen's current color precision.
+func (s *Screen) quantize(c Color) Color {
+	if !s.trueColor {
+		c = ansi256Color(toANSI256(c))
+	}
+	if s.colorStep <= 1 {
+		return c
+	}
+	step := s.colorStep
+	round := func(v uint8) uint8 {
+		return uint8(min((int(v)+step/2)/step*step, 255))
+	}
+	return Color{R: round(c.R), G: round(c.G), B: round(c.B)}
+}
+
+// writeColor writes ANSI color codes to the builder if needed.
+func (s *Screen) writeColor(c Color, isColorSet *bool, currentColor *Color) bool {
+	if !*isColorSet || c != *currentColor {
+		if s.trueColor {
+			s.buf = append(s.buf, "\x1b[38;2;"...)
+			s.buf = strconv.AppendUint(s.buf, uint64(c.R), 10)
+			s.buf = append(s.buf, ';')
+			s.buf = strconv.AppendUint(s.buf, uint64(c.G), 10)
+			s.buf = append(s.buf, ';')
+			s.buf = strconv.AppendUint(s.buf, uint64(c.B), 10)
+		} else {
+			s.buf = append(s.buf, "\x1b[38;5;"...)
+			s.buf = strconv.AppendInt(s.buf, int64(toANSI256(c)), 10)
+		}
+		s.buf = append(s.buf, 'm')
+		*currentColor = c
+		*isColorSet = true
+		return true
+	}
+	return false
+}
+
+// fullRender draws the entire frame to the terminal.
+func (s *Screen) fullRender(frame *Frame) {
+	s.begin()
+	s.buf = append(s.buf, "\x1b[H"...) // Move cursor to top-left
 	var currentColor Color
 	isColorSet := false
 
-	for row := 0; row < frame.height; row++ {
-		for col := 0; col < frame.width; col++ {
+	for row := 0; row < frame.height; row++ {
+		for col := 0; col < frame.width; col++ {
+			if frame.isBackground[row][col] {
+				if isColorSet {
+					s.buf = append(s.buf, "\x1b[0m"...) // Reset color
+					isColorSet = false
+				}
+			} else if col == 0 || frame.colors[row][col] != frame.colors[row][col-1] {
+				s.writeColor(s.quantize(frame.colors[row][col]), &isColorSet, &currentColor)
+			}
+			s.buf = utf8.AppendRune(s.buf, frame.characters[row][col])
+		}
+		if row < frame.height-1 {
+			s.buf = append(s.buf, "\r\n"...)
+		}
+	}
+	if isColorSet {
+		s.buf = append(s.buf, "\x1b[0m"...) // Reset color at end
+	}
+	s.write()
+}
+
+// deltaRender draws only changed parts of the frame and records them as shown,
+// so cells skipped by coarser diffing are retried later. Columns the frame
+// marks as unchanged are not looked at.
+func (s *Screen) deltaRender(frame *Frame) {
+	s.begin()
+	var currentColor Color
+	isColorSet := false
+	hasChanges := false
+
+	// Unchanged cells bridged over are rewritten as the terminal shows them,
+	// which in low-memory mode are the same cells of the new frame.
+	shown := s.previousFrame
+	if s.lowMem {
+		shown = frame
+	}
+	s.cols = s.cols[:0]
+	for col := 0; col < frame.width; col++ {
+		if frame.changed == nil || frame.changed[col] {
+			s.cols = append(s.cols, col)
+		}
+	}
+	cursorRow, cursorCol := -1, -1 // Where the terminal cursor is known to be
+	for row := 0; row < frame.height; row++ {
+		for _, col := range s.cols {
+			char := frame.characters[row][col]
+			color := s.quantize(frame.colors[row][col])
+			if !s.update(frame, row, col, char, color) {
+				continue
+			}
+			hasChanges = true
+			if s.canBridge(shown, row, cursorRow, cursorCol, col) {
+				// Rewriting a few unchanged cells is cheaper than a cursor move.
+				for c := cursorCol; c < col; c++ {
+					s.writeCell(shown.characters[row][c], s.quantize(shown.colors[row][c]), shown.isBackground[row][c], &isColorSet, &currentColor)
+				}
+			} else {
+				s.buf = append(s.buf, "\x1b["...)
+				s.buf = strconv.AppendInt(s.buf, int64(row+1), 10)
+				s.buf = append(s.buf, ';')
+				s.buf = strconv.AppendInt(s.buf, int64(col+1), 10)
+				s.buf = append(s.buf, 'H')
+			}
+			s.writeCell(char, color, frame.isBackground[row][col], &isColorSet, &currentColor)
+			cursorRow, cursorCol = row, col+1
+			if runeWidth(char) != 1 {
+				cursorRow = -1 // The terminal's advance for this glyph is uncertain
+			}
+		}
+	}
+	if hasChanges {
+		if isColorSet {
+			s.buf = append(s.buf, "\x1b[0m"...)
+		}
+		s.write()
+	}
+}
+
+// canBridge reports whether the cells between the cursor and col can be
+// rewritten in place of a cursor move, which requires diff batching to be
+// enabled and every bridged glyph to be a single column wide.
+func (s *Screen) canBridge(shown *Frame, row, cursorRow, cursorCol, col int) bool {
+	if s.batchGap == 0 || row != cursorRow || col < cursorCol || col-cursorCol > s.batchGap {
+		return false
+	}
+	for c := cursorCol; c < col; c++ {
+		if runeWidth(shown.characters[row][c]) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// writeCell writes a single cell, switching colors only when needed.
+func (s *Screen) writeCell(char rune, c Color, isBackground bool, isColorSet *bool, currentColor *Color) {
+	if isBackground {
+		if *isColorSet {
+			s.buf = append(s.buf, "\x1b[0m"...)
+			*isColorSet = false
+		}
+	} else {
+		s.writeColor(c, isColorSet, currentColor)
+	}
+	s.buf = utf8.AppendRune(s.buf, char)
+}
+
+// Print writes a frame as plain lines for a still image, without moving the
+// cursor or clearing the screen, so it can be captured into a file.
+func (s *Screen) Print(frame *Frame) {
+	s.buf = s.buf[:0]
+	var currentColor Color
+	for row := 0; row < frame.height; row++ {
+		isColorSet := false
+		last := frame.width - 1
+		for last >= 0 && frame.isBackground[row][last] {
+			last--
+		}
+		for col := 0; col <= last; col++ {
+			s.writeCell(frame.characters[row][col], s.quantize(frame.colors[row][col]), frame.isBackground[row][col], &isColorSet, &currentColor)
+		}
+		if isColorSet {
+			s.buf = append(s.buf, "\x1b[0m"...)
+		}
+		s.buf = append(s.buf, '\n')
+	}
+	s.out.Write(s.buf)
+}
+
+// copyFrame copies the source frame to the destination frame, storing colors
+// at the precision they were rendered with.
+func (s *Screen) copyFrame(src, dst *Frame) {
+	for r := range src.characters {
+		copy(dst.characters[r], src.characters[r])
+		copy(dst.colors[r], src.colors[r])
+		copy(dst.isBackground[r], src.isBackground[r])
+		if s.colorStep > 1 {
+			for c := range dst.colors[r] {
+				dst.colors[r][c] = s.quantize(dst.colors[r][c])
+			}
+		}
+	}
+}
+
+// === BANDWIDTH ===
+
+// Bandwidth governor tuning. Levels escalate from full fidelity through color
+// quantization and coarse diffing to halving the frame rate.
+const (
+	bandwidthSmoothing      = 0.2 // Weight of the newest frame in the rate average
+	bandwidthRelaxThreshold = 0.5 // Fraction of the budget below which fidelity is restored
+	bandwidthColorStep      = 48  // Color quantization step used from level 1
+	bandwidthCoarseLevel    = 2   // Level from which color-only changes are skipped
+	bandwidthFPSLevel       = 3   // Level from which the frame rate is halved per level
+)
+
+// BandwidthGovernor keeps the renderer's output under a byte budget by trading
+// visual fidelity for fewer bytes per second.
+type BandwidthGovernor struct {
+	budget   float64 // Allowed bytes per second
+	rate     float64 // Smoothed observed bytes per second
+	level    int     // Current degradation level
+	maxLevel int     // Level at which the frame rate reaches 1
+	baseFPS  int     // Configured frame rate
+	cooldown int     // Frames to wait before changing level again
+}
+
+// NewBandwidthGovernor creates a governor for the given budget and frame rate.
+func NewBandwidthGovernor(budget, fps int) *BandwidthGovernor {
+	maxLevel := bandwidthFPSLevel - 1
+	for f := fps; f > 1; f /= 2 {
+		maxLevel++
+	}
+	return &BandwidthGovernor{budget: float64(budget), maxLevel: maxLevel, baseFPS: fps}
+}
+
+// FPS returns the frame rate allowed at the current level.
+func (g *BandwidthGovernor) FPS() int {
+	if g.level < bandwidthFPSLevel {
+		return g.baseFPS
+	}
+	return max(g.baseFPS>>(g.level-bandwidthFPSLevel+1), 1)
+}
+
+// Observe records the bytes written for one frame and adjusts the screen's
+// rendering settings. It reports whether the frame rate changed.
+func (g *BandwidthGovernor) Observe(bytes int, screen *Screen) bool {
+	fps := g.FPS()
+	g.rate = g.rate*(1-bandwidthSmoothing) + float64(bytes*fps)*bandwidthSmoothing
+	if g.cooldown > 0 {
+		g.cooldown--
+		return false
+	}
+
+	level := g.level
+	switch {
+	case g.rate > g.budget && g.level < g.maxLevel:
+		level++
+	case g.rate < g.budget*bandwidthRelaxThreshold && g.level > 0:
+		level--
+	default:
+		return false
+	}
+	g.level = level
+	g.cooldown = g.FPS() // Give the new level about a second to settle
+	screen.skipColorOnly = level >= bandwidthCoarseLevel
+	screen.colorStep = 0
+	if level >= 1 {
+		screen.colorStep = bandwidthColorStep
+	}
+	return g.FPS() != fps
+}
+
+// === SYNC CHECK ===
+
+// mirrorTerminal reports the current size of another engine, keeping a shadow
+// engine the same size as the one it follows.
+type mirrorTerminal struct {
+	engine *Engine
+}
+
+func (t mirrorTerminal) Setup()   {}
+func (t mirrorTerminal) Restore() {}
+
+// GetSize returns the followed engine's dimensions.
+func (t mirrorTerminal) GetSize() (h, w int, err error) {
+	return t.engine.height, t.engine.width, nil
+}
+
+// replaySource is a math/rand source that counts its draws, so that another
+// source can be brought to the same state by replaying them. It yields the
+// same sequence as rand.NewSource.
+type replaySource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// newReplaySource creates a replaySource with the given seed.
+func newReplaySource(seed int64) *replaySource {
+	return &replaySource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+}
+
+func (s *replaySource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed, s.draws = seed, 0
+}
+
+func (s *replaySource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *replaySource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+// copyFrom brings the source to the state of other without disturbing it.
+func (s *replaySource) copyFrom(other *replaySource) {
+	s.Seed(other.seed)
+	for s.draws < other.draws {
+		s.Int63()
+	}
+}
+
+// SyncChecker runs a shadow engine in lockstep with the primary one and
+// compares frame checksums. The two simulations share a seed and inputs, so
+// any divergence points to nondeterminism in the engine.
+type SyncChecker struct {
+	shadow       *Engine
+	shadowSource *replaySource
+	source       *replaySource // Random source of the primary engine, only ever read
+	resync       bool
+	frames       int
+	diverged     int
+}
+
+// NewSyncChecker creates a shadow of primary from the same configuration.
+// The source is the one primary draws from.
+func NewSyncChecker(cfg *Config, primary *Engine, source *replaySource, resync bool) (*SyncChecker, error) {
+	shadowSource := newReplaySource(cfg.Seed)
+	shadow, err := NewEngine(cfg, rand.New(shadowSource), mirrorTerminal{engine: primary})
+	if err != nil {
+		return nil, err
+	}
+	if err := shadow.Resize(primary.height, primary.width); err != nil {
+		return nil, err
+	}
+	return &SyncChecker{shadow: shadow, shadowSource: shadowSource, source: source, resync: resync}, nil
+}
+
+// Check advances the shadow engine with the primary's inputs and compares its
+// frame against the primary's.
+func (c *SyncChecker) Check(primary *Engine, frame *Frame) error {
+	c.shadow.mirrorControls(primary)
+	shadowFrame, err := c.shadow.NextFrame()
+	if err != nil {
+		return fmt.Errorf("shadow engine failed: %w", err)
+	}
+	c.frames++
+	want, got := frame.Checksum(), shadowFrame.Checksum()
+	if want == got {
+		return nil
+	}
+	c.diverged++
+	log.Printf("Frame %d diverged: checksum %016x, shadow %016x", c.frames, want, got)
+	if c.resync {
+		c.shadow.copyState(primary)
+		c.shadowSource.copyFrom(c.source)
+	}
+	return nil
+}
+
+// === LATENCY ===
+
+// Latency histogram layout: values are recorded in microseconds, with every
+// power-of-two range split into latencySubBuckets linear sub-buckets.
+const (
+	latencySubBucketBits = 6
+	latencySubBuckets    = 1 << latencySubBucketBits
+)
+
+// LatencyHistogram records durations in the manner of an HDR histogram, keeping
+// a constant relative precision (~1.5%) from microseconds up to minutes.
+type LatencyHistogram struct {
+	counts []uint64
+	total  uint64
+	max    time.Duration
+}
+
+// NewLatencyHistogram creates an empty LatencyHistogram.
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram{counts: make([]uint64, 2*latencySubBuckets)}
+}
+
+// Record adds a duration to the histogram. Negative durations count as zero.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	idx := latencyBucketIndex(uint64(d / time.Microsecond))
+	if idx >= len(h.counts) {
+		grown := make([]uint64, idx+latencySubBuckets)
+		copy(grown, h.counts)
+		h.counts = grown
+	}
+	h.counts[idx]++
+	h.total++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Count returns the number of recorded values.
+func (h *LatencyHistogram) Count() uint64 {
+	return h.total
+}
+
+// Max returns the largest recorded duration.
+func (h *LatencyHistogram) Max() time.Duration {
+	return h.max
+}
+
+// Percentile returns the duration at or below which the given fraction (0-1)
+// of recorded values fall.
+func (h *LatencyHistogram) Percentile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var seen uint64
+	for idx, n := range h.counts {
+		seen += n
+		if seen >= target {
+			d := time.Duration(latencyBucketValue(idx)) * time.Microsecond
+			if d > h.max {
+				return h.max
+			}
+			return d
+		}
+	}
+	return h.max
+}
+
+// latencyBucketIndex maps a value in microseconds to its bucket index.
+func latencyBucketIndex(v uint64) int {
+	if v < 2*latencySubBuckets {
+		return int(v)
+	}
+	exp := bits.Len64(v) - latencySubBucketBits - 1
+	return exp<<latencySubBucketBits + int(v>>exp)
+}
+
+// latencyBucketValue returns the midpoint, in microseconds, of a bucket.
+func latencyBucketValue(idx int) uint64 {
+	if idx < 2*latencySubBuckets {
+		return uint64(idx)
+	}
+	exp := idx>>latencySubBucketBits - 1
+	sub := uint64(idx - exp<<latencySubBucketBits)
+	return sub<<exp + (1<<exp)/2
+}
+
+// === STATS ===
+
+// statsSampleInterval is how often memory use is sampled. Reading the memory
+// statistics briefly stops the world, so it is not done every frame.
+const statsSampleInterval = time.Second
+
+// SessionStats accumulates the figures summarized on exit with --stats.
+type SessionStats struct {
+	started    time.Time
+	frames     int
+	lastSample time.Time
+	peakHeap   uint64 // Largest live heap seen
+	sys        uint64 // Memory obtained from the OS
+}
+
+// StatsSummary is the exit summary, as written by --stats-out.
+type StatsSummary struct {
+	RuntimeSeconds float64 `json:"runtime_seconds"`
+	Frames         int     `json:"frames"`
+	AverageFPS     float64 `json:"average_fps"`
+	BytesWritten   int64   `json:"bytes_written"`
+	PeakHeapBytes  uint64  `json:"peak_heap_bytes"`
+	SysBytes       uint64  `json:"sys_bytes"`
+}
+
+// NewSessionStats starts measuring a session at the given time.
+func NewSessionStats(now time.Time) *SessionStats {
+	s := &SessionStats{started: now}
+	s.sample(now)
+	return s
+}
+
+// Frame counts a drawn frame, sampling memory use now and then.
+func (s *SessionStats) Frame(now time.Time) {
+	s.frames++
+	if now.Sub(s.lastSample) >= statsSampleInterval {
+		s.sample(now)
+	}
+}
+
+// sample records the current memory use.
+func (s *SessionStats) sample(now time.Time) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc > s.peakHeap {
+		s.peakHeap = ms.HeapAlloc
+	}
+	s.sys = ms.Sys
+	s.lastSample = now
+}
+
+// Summary returns the figures for the session up to now.
+func (s *SessionStats) Summary(now time.Time, bytesWritten int64) StatsSummary {
+	s.sample(now)
+	elapsed := now.Sub(s.started).Seconds()
+	summary := StatsSummary{
+		RuntimeSeconds: elapsed,
+		Frames:         s.frames,
+		BytesWritten:   bytesWritten,
+		PeakHeapBytes:  s.peakHeap,
+		SysBytes:       s.sys,
+	}
+	if elapsed > 0 {
+		summary.AverageFPS = float64(s.frames) / elapsed
+	}
+	return summary
+}
+
+// === WATCHDOG ===
+
+// errStalled is returned for output dropped while the sink is stalled.
+var errStalled = errors.New("output stalled")
+
+// StallWriter hands writes to the sink from a separate goroutine so that a
+// hung pty or blocked pipe cannot freeze the animation. A write that does not
+// finish within the timeout is abandoned, and output is dropped until the
+// sink accepts the abandoned write; nothing is queued, so no stale frames
+// reach the terminal once it recovers.
+type StallWriter struct {
+	timeout time.Duration
+	pending chan []byte
+	done    chan error
+	busy    bool // An abandoned write is still in flight
+	dropped bool // Output was lost since the last Resumed
+}
+
+// NewStallWriter starts the goroutine that writes to sink.
+func NewStallWriter(sink io.Writer, timeout time.Duration) *StallWriter {
+	w := &StallWriter{
+		timeout: timeout,
+		pending: make(chan []byte, 1),
+		done:    make(chan error, 1),
+	}
+	go func() {
+		for b := range w.pending {
+			_, err := sink.Write(b)
+			w.done <- err
+		}
+	}()
+	return w
+}
+
+// Write passes b to the sink, giving up after the timeout.
+func (w *StallWriter) Write(b []byte) (int, error) {
+	if w.Stalled() {
+		w.dropped = true
+		return 0, errStalled
+	}
+	// Copy, since the sink may still be reading after the caller moved on.
+	w.pending <- append([]byte(nil), b...)
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-w.done:
+		if err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	case <-timer.C:
+		w.busy, w.dropped = true, true
+		return 0, errStalled
+	}
+}
+
+// Stalled reports whether an abandoned write is still blocking the sink.
+func (w *StallWriter) Stalled() bool {
+	if w.busy {
+		select {
+		case <-w.done:
+			w.busy = false
+		default:
+		}
+	}
+	return w.busy
+}
+
+// Resumed reports, once, that the sink is accepting writes again after
+// output was lost, meaning the terminal state can no longer be trusted.
+func (w *StallWriter) Resumed() bool {
+	if !w.dropped || w.Stalled() {
+		return false
+	}
+	w.dropped = false
+	return true
+}
+
+// watchdogFrames is how many frame intervals the watchdog timeout must span at
+// least, so that a low frame rate is never mistaken for a stall.
+const watchdogFrames = 3
+
+// FrameWatchdog watches the frame loop from a goroutine of its own, so that a
+// loop stuck producing a frame is caught even though it cannot notice that
+// itself. The loop acts on what was caught once it gets going again.
+type FrameWatchdog struct {
+	timeout  time.Duration
+	beat     atomic.Int64 // Time the loop last showed signs of life, in Unix nanoseconds
+	interval atomic.Int64 // Current frame interval
+	stall    atomic.Int64 // Longest stall caught since the last call to Stall
+}
+
+// NewFrameWatchdog starts watching a loop running at the given frame interval
+// until ctx is done.
+func NewFrameWatchdog(ctx context.Context, timeout, interval time.Duration) *FrameWatchdog {
+	w := &FrameWatchdog{timeout: timeout}
+	w.SetInterval(interval)
+	w.Beat()
+	go w.watch(ctx)
+	return w
+}
+
+// Beat records that the frame loop is alive.
+func (w *FrameWatchdog) Beat() {
+	w.beat.Store(time.Now().UnixNano())
+}
+
+// SetInterval updates the frame interval after a frame rate change.
+func (w *FrameWatchdog) SetInterval(interval time.Duration) {
+	w.interval.Store(int64(interval))
+}
+
+// Stall reports, once, the longest stall caught since the last call, or 0.
+func (w *FrameWatchdog) Stall() time.Duration {
+	return time.Duration(w.stall.Swap(0))
+}
+
+// watch checks the heartbeat a few times per timeout. The limit never drops
+// below watchdogFrames frame intervals, as the bandwidth governor may lower
+// the frame rate well below the configured one.
+func (w *FrameWatchdog) watch(ctx context.Context) {
+	tick := time.NewTicker(w.timeout / 4)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			limit := time.Duration(w.interval.Load()) * watchdogFrames
+			if limit < w.timeout {
+				limit = w.timeout
+			}
+			gap := now.Sub(time.Unix(0, w.beat.Load()))
+			if gap > limit && int64(gap) > w.stall.Load() {
+				w.stall.Store(int64(gap))
+			}
+		}
+	}
+}
+
+// === INPUT ===
+
+// InputEvent is a key press or, with mouse tracking on, a mouse report.
+type InputEvent struct {
+	Key      byte // Key pressed, for keyboard events
+	Mouse    bool // Whether the event is a mouse report
+	Row, Col int  // Zero-based cell under the pointer
+	Button   int  // Button code of the report; 32 is added while dragging
+	Pressed  bool // False when a button was released
+}
+
+// mousePrefix introduces an SGR mouse report: ESC [ < button ; x ; y M (m
+// on release).
+const mousePrefix = "\x1b[<"
+
+// readInput decodes bytes read from in into events on the returned channel,
+// closing it when reading fails.
+func readInput(in io.Reader) <-chan InputEvent {
+	events := make(chan InputEvent, 16)
+	go func() {
+		defer close(events)
+		buf := make([]byte, 64)
+		var pending []byte
+		for {
+			n, err := in.Read(buf)
+			pending = append(pending, buf[:n]...)
+			for len(pending) > 0 {
+				event, size := parseInput(pending)
+				if size == 0 {
+					break // Wait for the rest of a split mouse report
+				}
+				events <- event
+				pending = pending[size:]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return events
+}
+
+// parseInput decodes the event at the start of b and returns it with the
+// number of bytes it consumed, or 0 if b holds only part of a mouse report.
+// Anything that is not a mouse report is passed on a byte at a time.
+func parseInput(b []byte) (InputEvent, int) {
+	key := InputEvent{Key: b[0]}
+	if len(b) < len(mousePrefix) {
+		if strings.HasPrefix(mousePrefix, string(b)) {
+			return InputEvent{}, 0
+		}
+		return key, 1
+	}
+	if string(b[:len(mousePrefix)]) != mousePrefix {
+		return key, 1
+	}
+	end := len(mousePrefix)
+	for end < len(b) && (b[end] == ';' || (b[end] >= '0' && b[end] <= '9')) {
+		end++
+	}
+	if end == len(b) {
+		return InputEvent{}, 0
+	}
+	fields := strings.Split(string(b[len(mousePrefix):end]), ";")
+	if (b[end] != 'M' && b[end] != 'm') || len(fields) != 3 {
+		return key, 1
+	}
+	var values [3]int
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return key, 1
+		}
+		values[i] = v
+	}
+	return InputEvent{
+		Mouse:   true,
+		Button:  values[0],
+		Col:     values[1] - 1,
+		Row:     values[2] - 1,
+		Pressed: b[end] == 'M',
+	}, end + 1
+}
+
+// === GRAVITY ===
+
+// Shape of a gravity well: how far its pull reaches, in rows, how strongly
+// it pulls at its center, how long it takes to reach full strength, and what
+// fraction of the way to its bent position a drop head moves each step.
+const (
+	gravityRadius  = 8.0
+	gravityMaxPull = 0.9
+	gravityRampUp  = 300 * time.Millisecond
+	gravityFollow  = 0.5
+)
+
+// GravityWell follows the pointer while a mouse button is held.
+type GravityWell struct {
+	row, col int
+	held     bool
+	since    time.Time // When the button went down
+}
+
+// Press moves the well to a cell, switching it on if the button just went
+// down.
+func (g *GravityWell) Press(row, col int, now time.Time) {
+	if !g.held {
+		g.held, g.since = true, now
+	}
+	g.row, g.col = row, col
+}
+
+// Release switches the well off.
+func (g *GravityWell) Release() {
+	g.held = false
+}
+
+// Pull returns the strength of the well, easing in after the button goes down.
+func (g *GravityWell) Pull(now time.Time) float64 {
+	if !g.held {
+		return 0
+	}
+	return gravityMaxPull * smoothstep(float64(now.Sub(g.since))/float64(gravityRampUp))
+}
+
+// === BULLET TIME ===
+
+// Bullet time envelope: ease into slow motion, hold, then ease back out.
+const (
+	bulletTimeScale   = 0.1
+	bulletTimeEaseIn  = 500 * time.Millisecond
+	bulletTimeHold    = 3 * time.Second
+	bulletTimeEaseOut = 1500 * time.Millisecond
+)
+
+// BulletTime drives a temporary slow-motion effect on the simulation clock.
+type BulletTime struct {
+	start  time.Time
+	active bool
+}
+
+// Trigger starts bullet time at the given moment. If it is already running,
+// it carries on from the current scale instead of jumping back to full speed:
+// an ease-in continues, a hold starts over, and an ease-out turns back into
+// an ease-in from where it is.
+func (b *BulletTime) Trigger(now time.Time) {
+	if !b.active {
+		b.start = now
+		b.active = true
+		return
+	}
+	elapsed := now.Sub(b.start)
+	switch {
+	case elapsed < bulletTimeEaseIn:
+	case elapsed < bulletTimeEaseIn+bulletTimeHold:
+		b.start = now.Add(-bulletTimeEaseIn)
+	case elapsed < bulletTimeEaseIn+bulletTimeHold+bulletTimeEaseOut:
+		// Smoothstep is symmetric, so the ease-in reaches the current scale
+		// the same fraction of the way from its start as the ease-out is
+		// from its end.
+		remaining := bulletTimeEaseIn + bulletTimeHold + bulletTimeEaseOut - elapsed
+		b.start = now.Add(-bulletTimeEaseIn * remaining / bulletTimeEaseOut)
+	default:
+		b.start = now
+	}
+}
+
+// Scale returns the simulation time scale at the given moment.
+func (b *BulletTime) Scale(now time.Time) float64 {
+	if !b.active {
+		return 1
+	}
+	elapsed := now.Sub(b.start)
+	switch {
+	case elapsed < bulletTimeEaseIn:
+		return 1 - (1-bulletTimeScale)*smoothstep(float64(elapsed)/float64(bulletTimeEaseIn))
+	case elapsed < bulletTimeEaseIn+bulletTimeHold:
+		return bulletTimeScale
+	case elapsed < bulletTimeEaseIn+bulletTimeHold+bulletTimeEaseOut:
+		t := float64(elapsed-bulletTimeEaseIn-bulletTimeHold) / float64(bulletTimeEaseOut)
+		return bulletTimeScale + (1-bulletTimeScale)*smoothstep(t)
+	}
+	b.active = false
+	return 1
+}
+
+// === NIGHT MODE ===
+
+// NightFilter warms frame colors like redshift/f.lux, either during a daily
+// window of hours or when toggled by hand.
+type NightFilter struct {
+	scheduled  bool       // Follow the start/end hours
+	start, end int        // Night hours; the window may wrap past midnight
+	factors    [3]float64 // Per-channel multipliers for the color temperature
+	forced     *bool      // Manual override set by Toggle, nil to follow the schedule
+}
+
+// NewNightFilter creates a NightFilter from the configuration.
+func NewNightFilter(cfg *Config) *NightFilter {
+	return &NightFilter{
+		scheduled: cfg.NightMode,
+		start:     cfg.NightStart,
+		end:       cfg.NightEnd,
+		factors:   kelvinFactors(cfg.NightTemp),
+	}
+}
+
+// Active reports whether the filter applies at the given time.
+func (n *NightFilter) Active(now time.Time) bool {
+	if n.forced != nil {
+		return *n.forced
+	}
+	if !n.scheduled {
+		return false
+	}
+	hour := now.Hour()
+	if n.start <= n.end {
+		return hour >= n.start && hour < n.end
+	}
+	return hour >= n.start || hour < n.end
+}
+
+// Toggle flips the filter by hand, overriding the schedule.
+func (n *NightFilter) Toggle(now time.Time) {
+	active := !n.Active(now)
+	n.forced = &active
+}
+
+// Apply warms every colored cell of the frame.
+func (n *NightFilter) Apply(frame *Frame) {
+	frame.touch()
+	for row := range frame.colors {
+		for col, c := range frame.colors[row] {
 			if frame.isBackground[row][col] {
-				if isColorSet {
-					b.WriteString("\x1b[0m") // Reset color
-					isColorSet = false
-				}
-			} else if col == 0 || frame.colors[row][col] != frame.colors[row][col-1] {
-				s.writeColor(&b, frame.colors[row][col], &isColorSet, &currentColor)
+				continue
+			}
+			frame.colors[row][col] = Color{
+				R: uint8(float64(c.R) * n.factors[0]),
+				G: uint8(float64(c.G) * n.factors[1]),
+				B: uint8(float64(c.B) * n.factors[2]),
 			}
-			b.WriteRune(frame.characters[row][col])
 		}
-		if row < frame.height-1 {
-			b.WriteString("\r\n")
+	}
+}
+
+// === OVERLAY ===
+
+// Panel layout and shading.
+const (
+	panelMargin = 1    // Cells between the panel and the screen edge
+	panelShade  = 0.35 // Brightness of the rain showing through the panel
+)
+
+// Panel is a block of text drawn over the top-right corner of a frame. Around
+// the text the rain is dimmed rather than hidden, so the panel looks
+// translucent.
+type Panel struct {
+	lines []string
+	color Color
+}
+
+// Draw renders the panel onto the frame, clipping it to the frame's bounds.
+func (p *Panel) Draw(frame *Frame) {
+	frame.touch()
+	width := 0
+	for _, line := range p.lines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+	left := max(frame.width-width-2*panelMargin-1, 0)
+	for i, line := range p.lines {
+		row := panelMargin + i
+		if row >= frame.height {
+			return
+		}
+		text := []rune(line)
+		for col := left; col < min(left+width+2*panelMargin, frame.width); col++ {
+			idx := col - left - panelMargin
+			if idx >= 0 && idx < len(text) {
+				frame.characters[row][col] = text[idx]
+				frame.colors[row][col] = p.color
+				frame.isBackground[row][col] = text[idx] == ' '
+			} else if !frame.isBackground[row][col] {
+				frame.colors[row][col] = dim(frame.colors[row][col], panelShade)
+			}
 		}
 	}
-	if isColorSet {
-		b.WriteString("\x1b[0m") // Reset color at end
+}
+
+// infoPanel lists the settings that produced the current animation.
+func infoPanel(cfg *Config) *Panel {
+	effect := "rain"
+	if len(cfg.Cipher) > 0 {
+		effect += " (transliterated dictionary)"
+	} else if len(cfg.Dictionary) > 0 {
+		effect += " (dictionary)"
+	}
+	if len(cfg.Backdrop) > 0 {
+		effect += " over backdrop"
+	}
+	if len(cfg.Layout) > 0 {
+		effect += fmt.Sprintf(" in %d zones", len(cfg.Layout))
+	}
+	return &Panel{
+		lines: []string{
+			"theme:   " + cfg.ColorName,
+			fmt.Sprintf("charset: %s (%d glyphs)", cfg.CharSetName, len(cfg.CharSet)),
+			fmt.Sprintf("fps:     %d", cfg.FPS),
+			fmt.Sprintf("density: %g", cfg.Density),
+			fmt.Sprintf("seed:    %d", cfg.Seed),
+			"effect:  " + effect,
+		},
+		color: Color{255, 255, 255},
 	}
-	s.out.Write([]byte(b.String()))
 }
 
-// deltaRender draws only changed parts of the frame.
-func (s *Screen) deltaRender(frame *Frame) {
-	var b strings.Builder
-	// Estimate: fewer cells change, so use a smaller initial size
-	b.Grow(frame.height * frame.width * 10)
-	var currentColor Color
-	isColorSet := false
-	hasChanges := false
+// === LAYOUT ===
 
-	for col := 0; col < frame.width; col++ {
-		for row := 0; row < frame.height; row++ {
-			if frame.characters[row][col] != s.previousFrame.characters[row][col] || frame.colors[row][col] != s.previousFrame.colors[row][col] {
-				hasChanges = true
-				b.WriteString(fmt.Sprintf("\x1b[%d;%dH", row+1, col+1))
-				if frame.isBackground[row][col] {
-					if isColorSet {
-						b.WriteString("\x1b[0m")
-						isColorSet = false
-					}
-				} else {
-					s.writeColor(&b, frame.colors[row][col], &isColorSet, &currentColor)
-				}
-				b.WriteRune(frame.characters[row][col])
+// Extent is a zone position or size, in cells or as a percentage of the
+// screen.
+type Extent struct {
+	Value   float64
+	Percent bool
+}
+
+// resolve converts the extent to cells on a screen dimension of total cells.
+func (x Extent) resolve(total int) int {
+	if x.Percent {
+		return int(math.Round(x.Value / 100 * float64(total)))
+	}
+	return int(x.Value)
+}
+
+// parseExtent reads an extent written as cells ("12") or a percentage ("70%").
+func parseExtent(raw string) (Extent, error) {
+	n, err := strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+	if err != nil || n < 0 {
+		return Extent{}, fmt.Errorf("invalid extent %q (want cells or a percentage)", raw)
+	}
+	return Extent{Value: n, Percent: strings.HasSuffix(raw, "%")}, nil
+}
+
+// Zone is a named rectangle of the screen with its own content: rain in its
+// own theme and charset, or a text effect, optionally with overlays.
+type Zone struct {
+	Name                string
+	X, Y, Width, Height Extent
+	Effect              string   // rain, clock, stats, info or blank
+	Theme               string   // Color theme, empty for the --color one
+	CharSet             string   // Character set, empty for the --chars one
+	Overlays            []string // Text panels drawn over the zone: clock, stats, info
+
+	color  Color  // Resolved theme
+	glyphs []rune // Resolved character set
+}
+
+// Effects a zone can show, and the text effects that also work as overlays.
+var (
+	zoneEffects  = map[string]bool{"rain": true, "clock": true, "stats": true, "info": true, "blank": true}
+	zoneOverlays = map[string]bool{"clock": true, "stats": true, "info": true}
+)
+
+// loadLayout reads and parses a layout file.
+func loadLayout(path string) ([]Zone, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layout: %w", err)
+	}
+	zones, err := parseLayout(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("layout %s: %w", path, err)
+	}
+	return zones, nil
+}
+
+// parseLayout parses a YAML layout file: a top-level "zones" list of maps.
+// For example:
+//
+//	zones:
+//	  - name: rain
+//	    width: 70%
+//	    overlays: [clock]
+//	  - name: side
+//	    x: 70%
+//	    width: 30%
+//	    effect: stats
+//
+// Zones cover the whole screen unless given a position and size, show rain
+// unless given an effect, and are drawn in order, later ones on top.
+func parseLayout(data string) ([]Zone, error) {
+	var doc struct {
+		Zones []Zone `yaml:"zones"`
+	}
+	decoder := yaml.NewDecoder(strings.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(doc.Zones) == 0 {
+		return nil, errors.New("no zones defined")
+	}
+	for i := range doc.Zones {
+		if doc.Zones[i].Name == "" {
+			doc.Zones[i].Name = fmt.Sprintf("zone%d", i+1)
+		}
+	}
+	return doc.Zones, nil
+}
+
+// UnmarshalYAML decodes a zone from a map of its settings, filling in the
+// defaults for the ones left out.
+func (z *Zone) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: a zone must be a map of settings", node.Line)
+	}
+	*z = Zone{
+		Width:  Extent{Value: 100, Percent: true},
+		Height: Extent{Value: 100, Percent: true},
+		Effect: "rain",
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if err := z.set(key.Value, value); err != nil {
+			return fmt.Errorf("line %d: %w", key.Line, err)
+		}
+	}
+	return nil
+}
+
+// set assigns one setting of a layout zone.
+func (z *Zone) set(key string, node *yaml.Node) error {
+	if key == "overlays" {
+		var overlays []string
+		if err := node.Decode(&overlays); err != nil {
+			return fmt.Errorf("overlays must be a list: %w", err)
+		}
+		for _, overlay := range overlays {
+			if !zoneOverlays[overlay] {
+				return fmt.Errorf("unknown overlay %q", overlay)
 			}
 		}
+		z.Overlays = overlays
+		return nil
 	}
-	if hasChanges {
-		if isColorSet {
-			b.WriteString("\x1b[0m")
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("%s must be a single value", key)
+	}
+	value := node.Value
+	var err error
+	switch key {
+	case "name":
+		z.Name = value
+	case "x":
+		z.X, err = parseExtent(value)
+	case "y":
+		z.Y, err = parseExtent(value)
+	case "width":
+		z.Width, err = parseExtent(value)
+	case "height":
+		z.Height, err = parseExtent(value)
+	case "effect":
+		if !zoneEffects[value] {
+			return fmt.Errorf("unknown effect %q", value)
 		}
-		s.out.Write([]byte(b.String()))
+		z.Effect = value
+	case "theme":
+		z.Theme = value
+	case "charset":
+		z.CharSet = value
+	default:
+		return fmt.Errorf("unknown zone key %q", key)
 	}
+	return err
 }
 
-// copyFrame copies the source frame to the destination frame.
-func (s *Screen) copyFrame(src, dst *Frame) {
-	for r := range src.characters {
-		copy(dst.characters[r], src.characters[r])
-		copy(dst.colors[r], src.colors[r])
-		copy(dst.isBackground[r], src.isBackground[r])
+// resolveZoneTheme looks up a zone's theme, falling back to the one chosen on
+// the command line. Its charset is resolved later by resolveGlyphs.
+func (p *ConfigParser) resolveZoneTheme(z *Zone, baseColor Color) error {
+	z.color = baseColor
+	if z.Theme != "" {
+		color, ok := p.configData.ColorThemes[strings.ToLower(z.Theme)]
+		if !ok {
+			return fmt.Errorf("zone %s: unknown color theme: %s", z.Name, z.Theme)
+		}
+		z.color = color
+	}
+	return nil
+}
+
+// zoneView is a zone placed on the current screen. It doubles as the
+// Terminal of the engine raining in it, reporting the zone's size.
+type zoneView struct {
+	Zone
+	rect   image.Rectangle // Cells covered on the current screen
+	engine *Engine         // Rain renderer, nil for text effects
+	frame  *Frame          // Text effect canvas, reused between frames
+}
+
+func (v *zoneView) Setup()   {}
+func (v *zoneView) Restore() {}
+
+// GetSize reports the zone's size on the current screen.
+func (v *zoneView) GetSize() (h, w int, err error) {
+	return v.rect.Dy(), v.rect.Dx(), nil
+}
+
+// Compositor renders each zone of a layout and assembles them into one
+// frame. Rain zones run their own engine, following the primary engine's
+// intensity, time scale and key toggles so that effects such as fade-in and
+// bullet time apply to the whole screen.
+type Compositor struct {
+	zones         []*zoneView
+	terminal      Terminal
+	height, width int  // Last size the terminal reported, or the still frame size
+	resilient     bool // Report terminal size errors instead of using the last size
+	frame         *Frame
+	info          []string     // Settings shown by info zones and overlays
+	bytesWritten  func() int64 // Output so far, shown by stats zones and overlays
+	frames        int
+	started       time.Time
+}
+
+// NewCompositor creates the engines for the rain zones of cfg.Layout.
+func NewCompositor(cfg *Config, random *rand.Rand, terminal Terminal, height, width int) (*Compositor, error) {
+	c := &Compositor{terminal: terminal, height: height, width: width, resilient: cfg.Resilient, info: infoPanel(cfg).lines}
+	for _, zone := range cfg.Layout {
+		view := &zoneView{Zone: zone}
+		if zone.Effect == "rain" {
+			zoneCfg := *cfg
+			zoneCfg.BaseColor, zoneCfg.CharSet = zone.color, zone.glyphs
+			engine, err := NewEngine(&zoneCfg, random, view)
+			if err != nil {
+				return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+			}
+			view.engine = engine
+		}
+		c.zones = append(c.zones, view)
+	}
+	return c, nil
+}
+
+// NextFrame advances every rain zone and returns the assembled frame.
+func (c *Compositor) NextFrame(primary *Engine) (*Frame, error) {
+	return c.render(primary, false)
+}
+
+// ComposeFrame returns a still frame with balanced drops in every rain zone.
+func (c *Compositor) ComposeFrame(primary *Engine) (*Frame, error) {
+	return c.render(primary, true)
+}
+
+// render lays the zones out on the current screen and draws them in order.
+func (c *Compositor) render(primary *Engine, still bool) (*Frame, error) {
+	h, w, err := c.terminal.GetSize()
+	if err != nil {
+		if c.resilient && !still {
+			return nil, fmt.Errorf("failed to get terminal size: %w", err)
+		}
+	} else {
+		c.height, c.width = h, w
+	}
+	height, width := c.height, c.width
+	if c.frame == nil || c.frame.height != height || c.frame.width != width {
+		c.frame = NewFrame(height, width)
+	}
+	c.frame.clear()
+	if c.frames == 0 {
+		c.started = time.Now()
+	}
+	c.frames++
+
+	screen := image.Rect(0, 0, width, height)
+	for _, zone := range c.zones {
+		x, y := zone.X.resolve(width), zone.Y.resolve(height)
+		zone.rect = image.Rect(x, y, x+zone.Width.resolve(width), y+zone.Height.resolve(height)).Intersect(screen)
+		if zone.rect.Empty() {
+			continue
+		}
+		frame, err := c.renderZone(zone, primary, still)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+		}
+		if len(zone.Overlays) > 0 {
+			var lines []string
+			for _, overlay := range zone.Overlays {
+				lines = append(lines, c.text(overlay)...)
+			}
+			(&Panel{lines: lines, color: zone.color}).Draw(frame)
+		}
+		blit(c.frame, frame, zone.rect.Min)
+	}
+	return c.frame, nil
+}
+
+// renderZone draws a single zone at its current size.
+func (c *Compositor) renderZone(zone *zoneView, primary *Engine, still bool) (*Frame, error) {
+	h, w := zone.rect.Dy(), zone.rect.Dx()
+	if e := zone.engine; e != nil {
+		if e.height != h || e.width != w {
+			if err := e.Resize(h, w); err != nil {
+				return nil, err
+			}
+		}
+		if e.origin != zone.rect.Min {
+			e.origin = zone.rect.Min
+			e.repaint()
+		}
+		e.mirrorControls(primary)
+		m := primary.manager
+		e.SetWell(m.wellRow-zone.rect.Min.Y, m.wellCol-zone.rect.Min.X, m.wellPull)
+		if still {
+			return e.ComposeFrame(), nil
+		}
+		return e.NextFrame()
+	}
+	if zone.frame == nil || zone.frame.height != h || zone.frame.width != w {
+		zone.frame = NewFrame(h, w)
+	}
+	zone.frame.clear()
+	drawCentered(zone.frame, c.text(zone.Effect), zone.color)
+	return zone.frame, nil
+}
+
+// text returns the lines shown by a text effect.
+func (c *Compositor) text(effect string) []string {
+	switch effect {
+	case "clock":
+		return []string{time.Now().Format("15:04:05")}
+	case "info":
+		return c.info
+	case "stats":
+		fps := 0.0
+		if elapsed := time.Since(c.started).Seconds(); elapsed > 0 {
+			fps = float64(c.frames-1) / elapsed // Intervals since the first frame
+		}
+		var written int64
+		if c.bytesWritten != nil {
+			written = c.bytesWritten()
+		}
+		return []string{
+			fmt.Sprintf("frames: %d", c.frames),
+			fmt.Sprintf("fps:    %.1f", fps),
+			fmt.Sprintf("output: %d KiB", written/1024),
+		}
+	}
+	return nil
+}
+
+// drawCentered writes lines in the middle of a frame, clipped to it.
+func drawCentered(frame *Frame, lines []string, color Color) {
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+	top, left := max((frame.height-len(lines))/2, 0), max((frame.width-width)/2, 0)
+	for i, line := range lines {
+		row := top + i
+		if row >= frame.height {
+			return
+		}
+		for j, r := range []rune(line) {
+			col := left + j
+			if col >= frame.width {
+				break
+			}
+			frame.characters[row][col] = r
+			frame.colors[row][col] = color
+			frame.isBackground[row][col] = r == ' '
+		}
+	}
+}
+
+// blit copies src into dst with its top-left corner at the given cell.
+func blit(dst, src *Frame, at image.Point) {
+	for row := 0; row < src.height && at.Y+row < dst.height; row++ {
+		n := min(src.width, dst.width-at.X)
+		copy(dst.characters[at.Y+row][at.X:], src.characters[row][:n])
+		copy(dst.colors[at.Y+row][at.X:], src.colors[row][:n])
+		copy(dst.isBackground[at.Y+row][at.X:], src.isBackground[row][:n])
 	}
 }
 
 // === MATRIX RAIN ===
 
+// Resilient mode retry policy: the delay doubles after each consecutive
+// failure, and the run ends once the failures persist past the limit.
+const (
+	resilientBackoff     = 250 * time.Millisecond
+	resilientMaxBackoff  = 4 * time.Second
+	resilientMaxFailures = 6
+)
+
 // MatrixRain holds the components of the Matrix rain animation.
 type MatrixRain struct {
 	engine   *Engine
@@ -642,24 +3505,80 @@ type MatrixRain struct {
 	terminal Terminal
 	ctx      context.Context
 	stop     context.CancelFunc
+	latency  *LatencyHistogram  // Frame scheduling latency, nil when not measured
+	governor *BandwidthGovernor // Output budget enforcement, nil when unlimited
+
+	startDelay time.Duration // Blank time before the first frame
+	fadeIn     time.Duration // Ramp from black after the start delay
+	bulletTime BulletTime    // Slow-motion effect triggered with the b key
+	info       *Panel        // Active settings, toggled with the i key
+	showInfo   bool
+
+	resilient bool   // Retry failed frames instead of exiting
+	failures  int    // Consecutive failed frames
+	lastFrame *Frame // Most recently drawn frame, for the error overlay
+
+	sync  *SyncChecker // Determinism check, nil when disabled
+	night *NightFilter // Warm palette, scheduled or toggled with the n key
+
+	static bool     // Print one composed frame instead of animating
+	png    string   // Write the composed frame to this PNG file instead of printing it
+	lock   *os.File // Lock on the terminal, if stdout is one
+
+	watchdog time.Duration // Longest tolerated stall, 0 when not watched
+	sink     *StallWriter  // Output guarded by the watchdog, nil when not watched
+
+	gravity *GravityWell // Pointer attractor, nil unless --gravity is on
+	layout  *Compositor  // Multi-zone screen, nil without --layout
+
+	stats     *SessionStats // Exit summary figures, nil when not requested
+	showStats bool          // Print the summary to stderr
+	statsOut  string        // Write the summary as JSON to this file
 }
 
 // NewMatrixRain creates and configures the Matrix rain animation.
-func NewMatrixRain(configData ConfigData, out io.Writer, random *rand.Rand) (*MatrixRain, error) {
+func NewMatrixRain(configData ConfigData, out io.Writer, source *replaySource) (rain *MatrixRain, err error) {
 	parser := NewConfigParser(configData)
 	cfg, err := parser.Parse()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	terminal := &StdTerminal{}
+	// Lock the terminal before probing it, so a second instance never mixes
+	// its probes into the output of the one already running.
+	var lock *os.File
+	if !cfg.Static {
+		if lock, err = lockTTY(cfg.Takeover); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil && lock != nil {
+				lock.Close()
+			}
+		}()
+	}
+	if err := parser.resolveGlyphs(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	var sink *StallWriter
+	if cfg.Watchdog > 0 {
+		sink = NewStallWriter(out, cfg.Watchdog)
+		out = sink
+	}
+	terminal := &StdTerminal{out: out, title: formatTitle(cfg), mouse: cfg.Gravity}
 	height, width, err := terminal.GetSize()
 	if err != nil {
-		return nil, fmt.Errorf("cannot get terminal size: %w", err)
+		if !cfg.Static {
+			return nil, fmt.Errorf("cannot get terminal size: %w", err)
+		}
+		height, width = defaultStaticHeight, defaultStaticWidth
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
+	source.Seed(cfg.Seed)
+	random := rand.New(source)
 	engine, err := NewEngine(cfg, random, terminal)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create engine: %w", err)
@@ -668,41 +3587,323 @@ func NewMatrixRain(configData ConfigData, out io.Writer, random *rand.Rand) (*Ma
 		return nil, fmt.Errorf("failed to resize engine: %w", err)
 	}
 	screen := NewScreen(out)
+	screen.trueColor = cfg.TrueColor
+	screen.syncUpdates = cfg.SyncUpdates
+	screen.batchGap = cfg.BatchGap
+	if cfg.LowMem {
+		screen.lowMem = true
+		debug.SetGCPercent(lowMemGCPercent)
+	}
 
-	return &MatrixRain{
+	rain = &MatrixRain{
 		engine:   engine,
 		screen:   screen,
 		terminal: terminal,
 		ctx:      ctx,
 		stop:     stop,
-	}, nil
+
+		startDelay: cfg.StartDelay,
+		fadeIn:     cfg.FadeIn,
+		info:       infoPanel(cfg),
+		resilient:  cfg.Resilient,
+		night:      NewNightFilter(cfg),
+		static:     cfg.Static,
+		lock:       lock,
+		png:        cfg.PNG,
+		showStats:  cfg.Stats,
+		statsOut:   cfg.StatsOut,
+		watchdog:   cfg.Watchdog,
+		sink:       sink,
+	}
+	if cfg.Latency {
+		rain.latency = NewLatencyHistogram()
+	}
+	if cfg.Gravity {
+		rain.gravity = &GravityWell{}
+	}
+	if cfg.MaxBandwidth > 0 {
+		rain.governor = NewBandwidthGovernor(cfg.MaxBandwidth, cfg.FPS)
+	}
+	if cfg.VerifySync {
+		if rain.sync, err = NewSyncChecker(cfg, engine, source, cfg.Resync); err != nil {
+			return nil, fmt.Errorf("failed to create sync checker: %w", err)
+		}
+	}
+	if len(cfg.Layout) > 0 {
+		if rain.layout, err = NewCompositor(cfg, random, terminal, height, width); err != nil {
+			return nil, fmt.Errorf("failed to create layout: %w", err)
+		}
+		rain.layout.bytesWritten = func() int64 { return screen.totalBytes }
+	}
+	return rain, nil
 }
 
 // Run starts the Matrix rain animation.
 func (r *MatrixRain) Run() error {
 	defer r.stop()
+	if r.static {
+		return r.printStatic()
+	}
+	if r.lock != nil {
+		defer r.lock.Close()
+	}
+	defer r.report()
 	defer r.terminal.Restore()
 
 	r.terminal.Setup()
 
+	if r.startDelay > 0 {
+		select {
+		case <-r.ctx.Done():
+			return nil
+		case <-time.After(r.startDelay):
+		}
+	}
+	started := time.Now()
+	if r.showStats || r.statsOut != "" {
+		r.stats = NewSessionStats(started)
+	}
+	if r.fadeIn > 0 {
+		r.engine.SetIntensity(0)
+	}
+	input := readInput(os.Stdin)
+
 	frameDuration := time.Second / time.Duration(r.engine.fps)
 	tick := time.NewTicker(frameDuration)
 	defer tick.Stop()
+	next := time.Now().Add(frameDuration)
+	var retryAt time.Time
+	var watchdog *FrameWatchdog
+	if r.watchdog > 0 {
+		watchdog = NewFrameWatchdog(r.ctx, r.watchdog, frameDuration)
+	}
 
 	for {
 		select {
 		case <-r.ctx.Done():
 			return nil
+		case event, ok := <-input:
+			if !ok {
+				input = nil // Stdin closed; stop listening for input
+				continue
+			}
+			if event.Mouse {
+				r.handleMouse(event)
+			} else {
+				r.handleKey(event.Key)
+			}
 		case <-tick.C:
-			frame, err := r.engine.NextFrame()
+			if r.latency != nil {
+				now := time.Now()
+				r.latency.Record(now.Sub(next))
+				// Skip over any slots the ticker dropped while we were late.
+				for !next.After(now) {
+					next = next.Add(frameDuration)
+				}
+			}
+			if watchdog != nil {
+				watchdog.Beat()
+				stall := watchdog.Stall()
+				if r.sink.Resumed() {
+					r.reinit("output resumed after a stall")
+				} else if stall > 0 && !r.sink.Stalled() {
+					r.reinit(fmt.Sprintf("no frame for %v", stall.Round(time.Millisecond)))
+				}
+			}
+			if time.Now().Before(retryAt) {
+				continue
+			}
+			if r.fadeIn > 0 {
+				r.engine.SetIntensity(smoothstep(float64(time.Since(started)) / float64(r.fadeIn)))
+			}
+			r.engine.SetTimeScale(r.bulletTime.Scale(time.Now()))
+			if r.gravity != nil {
+				r.engine.SetWell(r.gravity.row, r.gravity.col, r.gravity.Pull(time.Now()))
+			}
+			frame, err := r.nextFrame()
 			if err != nil {
-				return fmt.Errorf("failed to generate frame: %w", err)
+				if retryAt, err = r.retryAfter(err); err != nil {
+					return err
+				}
+				continue
+			}
+			r.failures = 0
+			if r.sync != nil {
+				if err := r.sync.Check(r.engine, frame); err != nil {
+					return err
+				}
 			}
+			r.decorate(frame, time.Now())
 			r.screen.Draw(frame)
+			r.lastFrame = frame
+			if r.stats != nil {
+				r.stats.Frame(time.Now())
+			}
+			if r.governor != nil && r.governor.Observe(r.screen.frameBytes, r.screen) {
+				frameDuration = time.Second / time.Duration(r.governor.FPS())
+				tick.Reset(frameDuration)
+				next = time.Now().Add(frameDuration)
+				if watchdog != nil {
+					watchdog.SetInterval(frameDuration)
+				}
+				if r.engine.debug {
+					log.Printf("Bandwidth governor switched to %d fps", r.governor.FPS())
+				}
+			}
+		}
+	}
+}
+
+// decorate draws the overlays over a rendered frame. Each of them must touch
+// the frame, as an interlaced engine redraws and the screen diffs only the
+// columns the simulation moved otherwise.
+func (r *MatrixRain) decorate(frame *Frame, now time.Time) {
+	if r.showInfo {
+		r.info.Draw(frame)
+	}
+	if r.night.Active(now) {
+		r.night.Apply(frame)
+	}
+}
+
+// printStatic prints a single composed frame without touching the terminal
+// state, for users who want the look without any motion.
+func (r *MatrixRain) printStatic() error {
+	var frame *Frame
+	if r.layout != nil {
+		var err error
+		if frame, err = r.layout.ComposeFrame(r.engine); err != nil {
+			return err
+		}
+	} else {
+		frame = r.engine.ComposeFrame()
+	}
+	if r.night.Active(time.Now()) {
+		r.night.Apply(frame)
+	}
+	if r.png != "" {
+		return writePNG(r.png, frame)
+	}
+	r.screen.Print(frame)
+	return nil
+}
+
+// retryAfter handles a failed frame. Outside resilient mode it ends the run;
+// otherwise it shows the error over the last frame and returns when to retry,
+// giving up after resilientMaxFailures consecutive failures.
+func (r *MatrixRain) retryAfter(err error) (time.Time, error) {
+	if !r.resilient {
+		return time.Time{}, fmt.Errorf("failed to generate frame: %w", err)
+	}
+	r.failures++
+	if r.failures > resilientMaxFailures {
+		return time.Time{}, fmt.Errorf("failed to generate frame after %d attempts: %w", r.failures, err)
+	}
+	backoff := resilientBackoff << (r.failures - 1)
+	if backoff > resilientMaxBackoff {
+		backoff = resilientMaxBackoff
+	}
+	if r.engine.debug {
+		log.Printf("Frame failed (attempt %d), retrying in %v: %v", r.failures, backoff, err)
+	}
+	if r.lastFrame != nil {
+		panel := &Panel{
+			lines: []string{
+				"error: " + err.Error(),
+				fmt.Sprintf("retrying in %v (attempt %d of %d)", backoff, r.failures, resilientMaxFailures),
+			},
+			color: Color{255, 64, 64},
+		}
+		panel.Draw(r.lastFrame)
+		r.screen.Draw(r.lastFrame)
+	}
+	return time.Now().Add(backoff), nil
+}
+
+// nextFrame produces the next frame of the animation, assembling the zones
+// when a layout is in use.
+func (r *MatrixRain) nextFrame() (*Frame, error) {
+	if r.layout != nil {
+		return r.layout.NextFrame(r.engine)
+	}
+	return r.engine.NextFrame()
+}
+
+// reinit recovers from a stall caught by the watchdog. The terminal may have
+// been reset or lost part of a frame in the meantime, so its modes are set up
+// afresh and the next frame is repainted in full.
+func (r *MatrixRain) reinit(reason string) {
+	if r.engine.debug {
+		log.Printf("Watchdog: re-initializing the terminal, %s", reason)
+	}
+	r.terminal.Restore()
+	r.terminal.Setup()
+	r.screen.Invalidate()
+}
+
+// handleKey reacts to a key pressed while the animation runs.
+func (r *MatrixRain) handleKey(key byte) {
+	switch key {
+	case 'b':
+		r.bulletTime.Trigger(time.Now())
+	case 'i':
+		r.showInfo = !r.showInfo
+	case 'n':
+		r.night.Toggle(time.Now())
+	case 'd':
+		r.engine.ToggleDecrypted()
+	}
+}
+
+// handleMouse moves the gravity well with the left button: pressing or
+// dragging pulls the drops toward the pointer and releasing lets them go.
+func (r *MatrixRain) handleMouse(event InputEvent) {
+	if r.gravity == nil || event.Button&^32 != 0 {
+		return
+	}
+	if event.Pressed {
+		r.gravity.Press(event.Row, event.Col, time.Now())
+	} else {
+		r.gravity.Release()
+	}
+}
+
+// report prints the measurements requested on the command line to stderr.
+// It must run after the terminal is restored so the report stays visible.
+func (r *MatrixRain) report() {
+	if r.latency != nil && r.latency.Count() > 0 {
+		fmt.Fprintf(os.Stderr, "frame latency: frames=%d p50=%v p99=%v max=%v\n",
+			r.latency.Count(), r.latency.Percentile(0.50), r.latency.Percentile(0.99), r.latency.Max())
+	}
+	if r.sync != nil {
+		fmt.Fprintf(os.Stderr, "sync check: frames=%d diverged=%d\n", r.sync.frames, r.sync.diverged)
+	}
+	if r.stats != nil {
+		summary := r.stats.Summary(time.Now(), r.screen.totalBytes)
+		if r.showStats {
+			fmt.Fprintf(os.Stderr, "session: runtime=%.1fs frames=%d avg_fps=%.1f bytes=%d peak_heap=%d sys=%d\n",
+				summary.RuntimeSeconds, summary.Frames, summary.AverageFPS, summary.BytesWritten, summary.PeakHeapBytes, summary.SysBytes)
+		}
+		if r.statsOut != "" {
+			if err := writeStats(r.statsOut, summary); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+			}
 		}
 	}
 }
 
+// writeStats saves a session summary as indented JSON.
+func writeStats(path string, summary StatsSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write stats: %w", err)
+	}
+	return nil
+}
+
 // === HELPERS ===
 
 // clamp limits a float64 value to a maximum, used for color calculations.
@@ -713,6 +3914,53 @@ func clamp(max, val float64) float64 {
 	return max
 }
 
+// goldenRank returns the n-th value of the golden ratio sequence, a
+// low-discrepancy sequence in [0, 1) whose consecutive values stay evenly
+// spread.
+func goldenRank(n int) float64 {
+	_, rank := math.Modf(float64(n+1) * math.Phi)
+	return rank
+}
+
+// smoothstep eases t from 0 to 1 with zero slope at both ends, clamping t to
+// that range first.
+func smoothstep(t float64) float64 {
+	t = math.Max(0, math.Min(1, t))
+	return t * t * (3 - 2*t)
+}
+
+// abs returns the absolute value of an integer.
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// runeWidth estimates how many terminal columns a rune occupies: 0 for
+// combining marks and joiners, 2 for East Asian wide and emoji, 1 otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r):
+		return 0
+	case r >= 0xFE00 && r <= 0xFE0F: // Variation selectors
+		return 0
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F, // CJK radicals through Yi
+		r >= 0xAC00 && r <= 0xD7A3,                // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF,                // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F,                // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60,                // Fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F, // Pictographs and emoticons
+		r >= 0x1F900 && r <= 0x1F9FF, // Supplemental symbols and pictographs
+		r >= 0x1F7E0 && r <= 0x1F7EB, // Colored circles and squares
+		r >= 0x20000 && r <= 0x3FFFD: // CJK extensions
+		return 2
+	}
+	return 1
+}
+
 // max returns the larger of two integers.
 func max(a, b int) int {
 	if a > b {
@@ -733,8 +3981,8 @@ func min(a, b int) int {
 
 func main() {
 	log.SetFlags(log.Lshortfile | log.Ltime)
-	random := rand.New(rand.NewSource(time.Now().UnixNano()))
-	rain, err := NewMatrixRain(defaultConfigData, os.Stdout, random)
+	source := newReplaySource(time.Now().UnixNano())
+	rain, err := NewMatrixRain(defaultConfigData, os.Stdout, source)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestParseLayout(t *testing.T) {
+	full := Extent{Value: 100, Percent: true}
+	tests := []struct {
+		name string
+		data string
+		want []Zone
+	}{
+		{
+			name: "defaults",
+			data: "zones:\n  - {}\n",
+			want: []Zone{{Name: "zone1", Width: full, Height: full, Effect: "rain"}},
+		},
+		{
+			name: "indented sequence",
+			data: `
+zones:
+  - name: rain
+    width: 70%
+    overlays: [clock, stats]
+  - name: side
+    x: 70%
+    width: 30%
+    effect: info
+`,
+			want: []Zone{
+				{Name: "rain", Width: Extent{Value: 70, Percent: true}, Height: full, Effect: "rain", Overlays: []string{"clock", "stats"}},
+				{Name: "side", X: Extent{Value: 70, Percent: true}, Width: Extent{Value: 30, Percent: true}, Height: full, Effect: "info"},
+			},
+		},
+		{
+			name: "zero-indent sequence",
+			data: "zones:\n- name: a\n  y: 2\n  height: 10\n",
+			want: []Zone{{Name: "a", Y: Extent{Value: 2}, Width: full, Height: Extent{Value: 10}, Effect: "rain"}},
+		},
+		{
+			name: "block list of overlays",
+			data: "zones:\n  - name: a\n    overlays:\n      - clock\n      - info\n",
+			want: []Zone{{Name: "a", Width: full, Height: full, Effect: "rain", Overlays: []string{"clock", "info"}}},
+		},
+		{
+			name: "comments and quoted hash",
+			data: "# dashboard\nzones:\n  - name: \"#1\" # first\n    theme: 'amber'\n    charset: auto\n",
+			want: []Zone{{Name: "#1", Width: full, Height: full, Effect: "rain", Theme: "amber", CharSet: "auto"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLayout(tt.data)
+			if err != nil {
+				t.Fatalf("parseLayout() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLayout() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "no zones defined"},
+		{"no zones", "zones: []\n", "no zones defined"},
+		{"unknown top-level key", "zone:\n  - name: a\n", "field zone not found"},
+		{"zone not a map", "zones:\n  - rain\n", "line 2: a zone must be a map"},
+		{"unknown key", "zones:\n  - name: a\n    colour: red\n", `line 3: unknown zone key "colour"`},
+		{"unknown effect", "zones:\n  - effect: snow\n", `line 2: unknown effect "snow"`},
+		{"unknown overlay", "zones:\n  - overlays: [clock, weather]\n", `line 2: unknown overlay "weather"`},
+		{"overlays not a list", "zones:\n  - overlays: {clock: true}\n", "line 2: overlays must be a list"},
+		{"bad extent", "zones:\n  - width: wide\n", `line 2: invalid extent "wide"`},
+		{"negative extent", "zones:\n  - x: -5\n", `line 2: invalid extent "-5"`},
+		{"list value", "zones:\n  - name: [a, b]\n", "line 2: name must be a single value"},
+		{"invalid yaml", "zones:\n  - name: a\n   x: 1\n", "yaml: line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseLayout(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseLayout() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{raw: "", want: 0},
+		{raw: "50kbps", want: 6250},
+		{raw: " 2Mbps ", want: 250000},
+		{raw: "1.5kbps", want: 187},
+		{raw: "8000", want: 1000},
+		{raw: "8bps", want: 1},
+		{raw: "12bps", want: 1},
+		{raw: "7bps", wantErr: true},
+		{raw: "0.001kbps", wantErr: true},
+		{raw: "0kbps", wantErr: true},
+		{raw: "-5kbps", wantErr: true},
+		{raw: "infkbps", wantErr: true},
+		{raw: "fast", wantErr: true},
+		{raw: "kbps", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseBandwidth(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseBandwidth(%q) = %d, want an error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseBandwidth(%q) = %d, %v, want %d", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// observeFrames feeds the governor frames of the given size and returns how
+// many times the frame rate changed.
+func observeFrames(g *BandwidthGovernor, screen *Screen, bytes, frames int) int {
+	changes := 0
+	for i := 0; i < frames; i++ {
+		if g.Observe(bytes, screen) {
+			changes++
+		}
+	}
+	return changes
+}
+
+func TestBandwidthGovernorObserve(t *testing.T) {
+	tests := []struct {
+		name          string
+		fps, budget   int
+		bytes, frames int
+		wantLevel     int
+		wantFPS       int
+		wantColorStep int
+		wantSkip      bool
+	}{
+		{name: "under budget", fps: 30, budget: 1000, bytes: 25, frames: 200, wantLevel: 0, wantFPS: 30},
+		{name: "first level quantizes colors", fps: 30, budget: 1000, bytes: 1000, frames: 1, wantLevel: 1, wantFPS: 30, wantColorStep: bandwidthColorStep},
+		{name: "cooldown holds the level", fps: 30, budget: 1000, bytes: 1000, frames: 30, wantLevel: 1, wantFPS: 30, wantColorStep: bandwidthColorStep},
+		{name: "coarse diffing", fps: 30, budget: 1000, bytes: 1000, frames: 32, wantLevel: 2, wantFPS: 30, wantColorStep: bandwidthColorStep, wantSkip: true},
+		{name: "halves the frame rate", fps: 30, budget: 1000, bytes: 1000, frames: 63, wantLevel: 3, wantFPS: 15, wantColorStep: bandwidthColorStep, wantSkip: true},
+		{name: "stops at 1 fps", fps: 30, budget: 1000, bytes: 5000, frames: 1000, wantLevel: 6, wantFPS: 1, wantColorStep: bandwidthColorStep, wantSkip: true},
+		{name: "1 fps is never lowered", fps: 1, budget: 10, bytes: 5000, frames: 100, wantLevel: 2, wantFPS: 1, wantColorStep: bandwidthColorStep, wantSkip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewBandwidthGovernor(tt.budget, tt.fps)
+			screen := NewScreen(io.Discard)
+			observeFrames(g, screen, tt.bytes, tt.frames)
+			if g.level != tt.wantLevel || g.FPS() != tt.wantFPS {
+				t.Errorf("level %d at %d fps, want level %d at %d fps", g.level, g.FPS(), tt.wantLevel, tt.wantFPS)
+			}
+			if screen.colorStep != tt.wantColorStep || screen.skipColorOnly != tt.wantSkip {
+				t.Errorf("color step %d, skip color-only %v, want %d, %v", screen.colorStep, screen.skipColorOnly, tt.wantColorStep, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestBandwidthGovernorRelaxes(t *testing.T) {
+	g := NewBandwidthGovernor(1000, 30)
+	screen := NewScreen(io.Discard)
+	if changes := observeFrames(g, screen, 5000, 1000); changes != 4 || g.FPS() != 1 {
+		t.Fatalf("escalation changed the frame rate %d times to %d fps, want 4 times to 1 fps", changes, g.FPS())
+	}
+	if changes := observeFrames(g, screen, 0, 1000); changes != 4 {
+		t.Errorf("relaxation changed the frame rate %d times, want 4", changes)
+	}
+	if g.level != 0 || g.FPS() != 30 || screen.colorStep != 0 || screen.skipColorOnly {
+		t.Errorf("relaxed to level %d at %d fps (color step %d, skip %v), want full fidelity at 30 fps",
+			g.level, g.FPS(), screen.colorStep, screen.skipColorOnly)
+	}
+}
+
+func TestBulletTimeRetrigger(t *testing.T) {
+	total := bulletTimeEaseIn + bulletTimeHold + bulletTimeEaseOut
+	start := time.Unix(0, 0)
+	for at := time.Duration(0); at < total+time.Second; at += 50 * time.Millisecond {
+		var b BulletTime
+		b.Trigger(start)
+		now := start.Add(at)
+		before := b.Scale(now)
+		b.Trigger(now)
+		if after := b.Scale(now); math.Abs(after-before) > 1e-6 {
+			t.Errorf("retrigger %v in: scale jumped from %.3f to %.3f", at, before, after)
+		}
+		if mid := b.Scale(now.Add(bulletTimeEaseIn)); mid != bulletTimeScale {
+			t.Errorf("retrigger %v in: scale %.3f an ease-in later, want %.1f", at, mid, bulletTimeScale)
+		}
+		if b.Scale(now.Add(total)) != 1 {
+			t.Errorf("retrigger %v in: still slowed down a full cycle later", at)
+		}
+	}
+}
+
+// fixedTerminal is a Terminal of a constant size.
+type fixedTerminal struct{ height, width int }
+
+func (t fixedTerminal) Setup()   {}
+func (t fixedTerminal) Restore() {}
+
+func (t fixedTerminal) GetSize() (h, w int, err error) {
+	return t.height, t.width, nil
+}
+
+// testRainConfig returns a valid configuration with a backdrop, so that
+// frames mix drops, backdrop text and blank cells.
+func testRainConfig() *Config {
+	return &Config{
+		BaseColor:        Color{0, 255, 0},
+		FPS:              30,
+		Density:          1.5,
+		CharSet:          []rune("abcdefghij0123"),
+		MinDropLength:    defaultMinDropLength,
+		MaxDropLength:    defaultMaxDropLength,
+		ReactivateChance: defaultReactivateChance,
+		PauseChance:      defaultPauseChance,
+		Backdrop:         [][]rune{[]rune("$ make test"), []rune("ok  hugo_rain  0.01s"), nil, []rune("$ _")},
+		BackdropOpacity:  defaultBackdropOpacity,
+		Opacity:          defaultOpacity,
+		ColorName:        "green",
+		CharSetName:      "custom",
+		Seed:             42,
+		NightTemp:        defaultNightTemp,
+		Interlace:        1,
+	}
+}
+
+// newTestRain creates the parts of a MatrixRain that produce and draw frames.
+func newTestRain(t *testing.T, cfg *Config, height, width int) *MatrixRain {
+	t.Helper()
+	engine, err := NewEngine(cfg, rand.New(rand.NewSource(cfg.Seed)), fixedTerminal{height, width})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Resize(height, width); err != nil {
+		t.Fatal(err)
+	}
+	return &MatrixRain{engine: engine, screen: NewScreen(io.Discard), info: infoPanel(cfg), night: NewNightFilter(cfg)}
+}
+
+// renderFrames runs the frame loop of Run for a fixed script of fade-in,
+// overlays, a gravity well and slow motion, passing each frame to check
+// before and after it is decorated.
+func renderFrames(r *MatrixRain, check func(i int, frame *Frame, decorated bool)) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 240; i++ {
+		r.engine.SetIntensity(float64(i) / 20)
+		switch i {
+		case 40, 80:
+			r.showInfo = !r.showInfo
+		case 60, 100:
+			r.night.Toggle(now)
+		}
+		pull := 0.0
+		if i >= 120 && i < 150 {
+			pull = gravityMaxPull
+		}
+		r.engine.SetWell(8, 30, pull)
+		scale := 1.0
+		if i >= 160 && i < 200 {
+			scale = 0.3
+		}
+		r.engine.SetTimeScale(scale)
+
+		frame, _ := r.engine.NextFrame()
+		check(i, frame, false)
+		r.decorate(frame, now)
+		check(i, frame, true)
+	}
+}
+
+// frameDiff describes the first cell where two frames differ, or returns "".
+func frameDiff(got, want *Frame) string {
+	for row := 0; row < want.height; row++ {
+		for col := 0; col < want.width; col++ {
+			g := fmt.Sprintf("%q %v %v", got.characters[row][col], got.colors[row][col], got.isBackground[row][col])
+			w := fmt.Sprintf("%q %v %v", want.characters[row][col], want.colors[row][col], want.isBackground[row][col])
+			if g != w {
+				return fmt.Sprintf("cell %d,%d is %s, want %s", row, col, g, w)
+			}
+		}
+	}
+	return ""
+}
+
+// TestInterlacedRenderMatchesFullRender guards the partial redraw of
+// interlaced engines, which relies on every overlay touching the frame.
+func TestInterlacedRenderMatchesFullRender(t *testing.T) {
+	cfg := testRainConfig()
+	cfg.Interlace = 4
+	partial := newTestRain(t, cfg, 24, 60)
+	full := newTestRain(t, cfg, 24, 60)
+
+	var frames []*Frame
+	renderFrames(full, func(i int, frame *Frame, decorated bool) {
+		if !decorated {
+			copied := NewFrame(frame.height, frame.width)
+			NewScreen(io.Discard).copyFrame(frame, copied)
+			frames = append(frames, copied)
+		}
+		full.engine.repaint()
+	})
+	renderFrames(partial, func(i int, frame *Frame, decorated bool) {
+		if decorated {
+			return
+		}
+		if diff := frameDiff(frame, frames[i]); diff != "" {
+			t.Fatalf("frame %d: partial redraw differs from full redraw: %s", i, diff)
+		}
+	})
+}
+
+// emulator is a minimal terminal understanding the output of Screen: cursor
+// positioning, SGR colors, and carriage return with line feed.
+type emulator struct {
+	cells    [][]emulatorCell
+	row, col int
+	color    Color
+	colored  bool
+}
+
+type emulatorCell struct {
+	char    rune
+	color   Color
+	colored bool
+}
+
+func newEmulator(height, width int) *emulator {
+	e := &emulator{cells: make([][]emulatorCell, height)}
+	for row := range e.cells {
+		e.cells[row] = make([]emulatorCell, width)
+		for col := range e.cells[row] {
+			e.cells[row][col].char = ' '
+		}
+	}
+	return e
+}
+
+func (e *emulator) Write(p []byte) (int, error) {
+	for s := string(p); s != ""; {
+		if strings.HasPrefix(s, "\x1b[") {
+			end := strings.IndexAny(s, "Hhlm")
+			if end < 0 {
+				return 0, fmt.Errorf("unterminated escape sequence %q", s)
+			}
+			e.escape(s[2:end], s[end])
+			s = s[end+1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		switch r {
+		case '\r':
+			e.col = 0
+		case '\n':
+			e.row++
+		default:
+			if e.row < len(e.cells) && e.col < len(e.cells[e.row]) {
+				e.cells[e.row][e.col] = emulatorCell{char: r, color: e.color, colored: e.colored}
+			}
+			e.col++
+		}
+	}
+	return len(p), nil
+}
+
+func (e *emulator) escape(params string, final byte) {
+	switch final {
+	case 'H':
+		e.row, e.col = 0, 0
+		if params != "" {
+			fmt.Sscanf(params, "%d;%d", &e.row, &e.col)
+			e.row, e.col = e.row-1, e.col-1
+		}
+	case 'm':
+		var r, g, b, idx int
+		switch {
+		case params == "0":
+			e.color, e.colored = Color{}, false
+		case strings.HasPrefix(params, "38;2;"):
+			fmt.Sscanf(params, "38;2;%d;%d;%d", &r, &g, &b)
+			e.color, e.colored = Color{uint8(r), uint8(g), uint8(b)}, true
+		case strings.HasPrefix(params, "38;5;"):
+			fmt.Sscanf(params, "38;5;%d", &idx)
+			e.color, e.colored = ansi256Color(idx), true
+		}
+	}
+}
+
+// TestDeltaRenderReplay replays the screen output of every frame through a
+// terminal emulator and checks that it shows the frame exactly.
+func TestDeltaRenderReplay(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(cfg *Config, screen *Screen)
+	}{
+		{"plain", func(cfg *Config, screen *Screen) {}},
+		{"batch gap", func(cfg *Config, screen *Screen) { screen.batchGap = remoteBatchGap }},
+		{"256 colors", func(cfg *Config, screen *Screen) { screen.trueColor = false }},
+		{"low mem", func(cfg *Config, screen *Screen) { screen.lowMem = true }},
+		{"interlaced", func(cfg *Config, screen *Screen) { cfg.Interlace = 4 }},
+		{"interlaced low mem with batch gap", func(cfg *Config, screen *Screen) {
+			cfg.Interlace = 4
+			screen.lowMem, screen.batchGap, screen.syncUpdates = true, remoteBatchGap, true
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const height, width = 24, 60
+			cfg := testRainConfig()
+			screen := NewScreen(nil)
+			tt.setup(cfg, screen)
+			r := newTestRain(t, cfg, height, width)
+			term := newEmulator(height, width)
+			screen.out = term
+			r.screen = screen
+
+			renderFrames(r, func(i int, frame *Frame, decorated bool) {
+				if !decorated {
+					return
+				}
+				r.screen.Draw(frame)
+				for row := 0; row < height; row++ {
+					for col := 0; col < width; col++ {
+						got := term.cells[row][col]
+						want := emulatorCell{char: frame.characters[row][col]}
+						if !frame.isBackground[row][col] {
+							want.color, want.colored = screen.quantize(frame.colors[row][col]), true
+						}
+						if got != want {
+							t.Fatalf("frame %d: terminal shows %+v at %d,%d, want %+v", i, got, row, col, want)
+						}
+					}
+				}
+			})
+		})
+	}
+}